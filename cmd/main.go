@@ -4,15 +4,23 @@ import (
 	"context"
 	httpserver "currency-exchange/internal/http"
 	"database/sql"
+	"encoding/json"
+	"flag"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"currency-exchange/internal/conversion"
+	"currency-exchange/internal/importer"
+	"currency-exchange/internal/provider"
 	"currency-exchange/internal/repository/db"
 	"currency-exchange/internal/service"
 
 	_ "github.com/lib/pq"
+	"github.com/shopspring/decimal"
 )
 
 func main() {
@@ -32,9 +40,34 @@ func main() {
 
 	ctx := context.Background()
 	currencyService := service.NewCurrencyService(ctx, currencyRepo)
-	exchangeService := service.NewExchangeService(ctx, exchangeRepo, currencyRepo)
+	exchangeService := service.NewExchangeService(ctx, exchangeRepo, currencyRepo, service.ExchangeConfig{
+		MaxHops:      getEnvIntOrDefault("EXCHANGE_MAX_HOPS", conversion.DefaultMaxHops),
+		RoundingMode: roundingModeFromEnv(),
+		Freshness:    freshnessPolicyFromEnv(),
+		Guardrail:    guardrailPolicyFromEnv(),
+	})
 
-	handler := httpserver.LoggingMiddleware(httpserver.New(currencyService, exchangeService))
+	if len(os.Args) > 1 && os.Args[1] == "catalog" {
+		runCatalogCommand(currencyService, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		runImportCommand(importer.New(ctx, exchangeRepo, currencyRepo, exchangeService), os.Args[2:])
+		return
+	}
+
+	registry := providerRegistryFromEnv()
+	aggregator, err := provider.NewAggregator(exchangeService, aggregatorConfigFromEnv(registry))
+	if err != nil {
+		log.Fatalf("invalid rate aggregation settings: %v", err)
+	}
+	scheduler := provider.NewScheduler(registry, aggregator, provider.SchedulerConfig{
+		MinBackoff: getEnvDurationOrDefault("RATE_PROVIDER_MIN_BACKOFF", provider.DefaultMinBackoff),
+		MaxBackoff: getEnvDurationOrDefault("RATE_PROVIDER_MAX_BACKOFF", provider.DefaultMaxBackoff),
+	})
+	scheduler.Start(ctx)
+
+	handler := httpserver.LoggingMiddleware(httpserver.New(currencyService, exchangeService, scheduler))
 
 	log.Printf("http server listening on %s", addr)
 	if err := http.ListenAndServe(addr, handler); err != nil {
@@ -42,6 +75,144 @@ func main() {
 	}
 }
 
+// runCatalogCommand implements `server catalog [seed|sync]`, loading the
+// embedded ISO 4217 catalog into the currencies table and printing a report.
+func runCatalogCommand(currencyService *service.CurrencyService, args []string) {
+	mode := service.CatalogSeed
+	if len(args) > 0 {
+		mode = args[0]
+	}
+	result, err := currencyService.SyncCatalog(mode)
+	if err != nil {
+		log.Fatalf("catalog %s error: %v", mode, err)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		log.Fatalf("catalog %s output error: %v", mode, err)
+	}
+}
+
+// runImportCommand implements
+// `server import <feed> [-history] [-override-reason text] [source]`,
+// loading official rates from a central bank feed into the rate book. source
+// is a URL or a file path and defaults to the feed's URL setting.
+func runImportCommand(rateImporter *importer.Importer, args []string) {
+	if len(args) == 0 {
+		log.Fatalf("import: feed name required (ecb, cbr, nbrb)")
+	}
+	name := args[0]
+	flags := flag.NewFlagSet("import "+name, flag.ExitOnError)
+	history := flags.Bool("history", false, "write every publication day into the rate history")
+	overrideReason := flags.String("override-reason", "", "write rates beyond the rate change guardrail, recording this reason")
+	_ = flags.Parse(args[1:])
+
+	var feed importer.Feed
+	switch name {
+	case "ecb":
+		feed = importer.ECBFeed{Location: importSource(flags, "ECB_URL", importer.ECBDailyURL)}
+	case "cbr":
+		feed = importer.CBRFeed{Location: importSource(flags, "CBR_URL", importer.CBRDailyURL)}
+	case "nbrb":
+		feed = importer.NBRBFeed{Location: importSource(flags, "NBRB_URL", importer.NBRBBaseURL)}
+	default:
+		log.Fatalf("import: unknown feed %q", name)
+	}
+
+	report, err := rateImporter.Import(feed, importer.Options{History: *history, OverrideReason: *overrideReason})
+	if err != nil {
+		log.Fatalf("import %s error: %v", name, err)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatalf("import %s output error: %v", name, err)
+	}
+}
+
+// importSource returns the source given on the command line, falling back
+// to the environment variable key and then to defaultURL.
+func importSource(flags *flag.FlagSet, key string, defaultURL string) string {
+	if flags.NArg() > 0 {
+		return flags.Arg(0)
+	}
+	return getEnvOrDefault(key, defaultURL)
+}
+
+// providerRegistryFromEnv registers the providers named in RATE_PROVIDERS
+// (comma separated: ecb, cbr, nbrb, file). Every provider is polled every
+// RATE_PROVIDER_INTERVAL with a RATE_PROVIDER_TIMEOUT per fetch;
+// RATE_PROVIDER_<NAME>_INTERVAL overrides the interval of one provider.
+func providerRegistryFromEnv() *provider.Registry {
+	registry := provider.NewRegistry()
+	interval := getEnvDurationOrDefault("RATE_PROVIDER_INTERVAL", time.Hour)
+	timeout := getEnvDurationOrDefault("RATE_PROVIDER_TIMEOUT", importer.DefaultTimeout)
+
+	for _, name := range strings.Split(os.Getenv("RATE_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		var (
+			rateProvider provider.RateProvider
+			base         string
+		)
+		switch name {
+		case "ecb":
+			base = "EUR"
+			feed := importer.ECBFeed{Location: getEnvOrDefault("ECB_URL", importer.ECBDailyURL)}
+			rateProvider = provider.FeedProvider{Feed: feed, Base: base}
+		case "cbr":
+			base = "RUB"
+			feed := importer.CBRFeed{Location: getEnvOrDefault("CBR_URL", importer.CBRDailyURL)}
+			rateProvider = provider.FeedProvider{Feed: feed, Base: base}
+		case "nbrb":
+			base = "BYN"
+			feed := importer.NBRBFeed{Location: getEnvOrDefault("NBRB_URL", importer.NBRBBaseURL)}
+			rateProvider = provider.FeedProvider{Feed: feed, Base: base}
+		case "file":
+			rateProvider = provider.FileProvider{Path: getEnvOrDefault("FILE_PROVIDER_PATH", "rates.json")}
+			base = getEnvOrDefault("FILE_PROVIDER_BASE", "USD")
+		default:
+			log.Fatalf("invalid RATE_PROVIDERS: unknown provider %q", name)
+		}
+		err := registry.Register(rateProvider, provider.Schedule{
+			Base:     base,
+			Interval: getEnvDurationOrDefault("RATE_PROVIDER_"+strings.ToUpper(name)+"_INTERVAL", interval),
+			Timeout:  timeout,
+		})
+		if err != nil {
+			log.Fatalf("invalid RATE_PROVIDERS: %v", err)
+		}
+	}
+	return registry
+}
+
+// aggregatorConfigFromEnv reads RATE_AGGREGATION_METHOD (median or
+// weighted_mean), RATE_AGGREGATION_WINDOW, RATE_AGGREGATION_MAX_DEVIATION,
+// RATE_AGGREGATION_MIN_SOURCES and the weight of every registered provider from RATE_PROVIDER_<NAME>_PRIORITY.
+func aggregatorConfigFromEnv(registry *provider.Registry) provider.AggregatorConfig {
+	maxDeviation := provider.DefaultMaxDeviation
+	if value := os.Getenv("RATE_AGGREGATION_MAX_DEVIATION"); value != "" {
+		parsed, err := decimal.NewFromString(value)
+		if err != nil {
+			log.Fatalf("invalid RATE_AGGREGATION_MAX_DEVIATION=%q: %v", value, err)
+		}
+		maxDeviation = parsed
+	}
+	priorities := make(map[string]int)
+	for _, name := range registry.Names() {
+		priorities[name] = getEnvIntOrDefault("RATE_PROVIDER_"+strings.ToUpper(name)+"_PRIORITY", 1)
+	}
+	return provider.AggregatorConfig{
+		Method:       getEnvOrDefault("RATE_AGGREGATION_METHOD", provider.AggregateMedian),
+		Window:       getEnvDurationOrDefault("RATE_AGGREGATION_WINDOW", provider.DefaultAggregationWindow),
+		MaxDeviation: maxDeviation,
+		MinSources:   getEnvIntOrDefault("RATE_AGGREGATION_MIN_SOURCES", provider.DefaultMinSources),
+		Priorities:   priorities,
+	}
+}
+
 func pingWithRetry(dbConn *sql.DB, attempts int, delay time.Duration) error {
 	var err error
 	for i := 0; i < attempts; i++ {
@@ -78,3 +249,92 @@ func getEnvOrDefault(key string, deafaultValue string) string {
 	}
 	return value
 }
+
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("invalid %s=%q: %v", key, value, err)
+	}
+	return parsed
+}
+
+func getEnvDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("invalid %s=%q: %v", key, value, err)
+	}
+	return parsed
+}
+
+// freshnessPolicyFromEnv reads RATE_MAX_AGE, RATE_MAX_AGE_PAIRS (comma
+// separated BASE/TARGET=duration overrides, e.g. USD/JPY=24h) and
+// RATE_STALE_POLICY (warn or reject).
+func freshnessPolicyFromEnv() service.FreshnessPolicy {
+	mode, err := service.ParseFreshnessMode(os.Getenv("RATE_STALE_POLICY"))
+	if err != nil {
+		log.Fatalf("invalid RATE_STALE_POLICY: %v", err)
+	}
+	pairs := make(map[string]time.Duration)
+	for _, entry := range strings.Split(os.Getenv("RATE_MAX_AGE_PAIRS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pair, value, ok := strings.Cut(entry, "=")
+		maxAge, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || err != nil || maxAge < 0 {
+			log.Fatalf("invalid RATE_MAX_AGE_PAIRS entry %q", entry)
+		}
+		pairs[strings.ToUpper(strings.TrimSpace(pair))] = maxAge
+	}
+	return service.FreshnessPolicy{
+		MaxAge:     getEnvDurationOrDefault("RATE_MAX_AGE", 0),
+		PairMaxAge: pairs,
+		Mode:       mode,
+	}
+}
+
+// guardrailPolicyFromEnv reads the largest accepted relative rate change,
+// e.g. RATE_MAX_CHANGE=0.2, and per pair limits such as
+// RATE_MAX_CHANGE_PAIRS=USD/JPY=0.1,EUR/USD=0.05, which also cover the
+// inverse pairs.
+func guardrailPolicyFromEnv() service.GuardrailPolicy {
+	maxChange := decimal.Zero
+	if value := strings.TrimSpace(os.Getenv("RATE_MAX_CHANGE")); value != "" {
+		parsed, err := decimal.NewFromString(value)
+		if err != nil || parsed.IsNegative() {
+			log.Fatalf("invalid RATE_MAX_CHANGE %q", value)
+		}
+		maxChange = parsed
+	}
+	pairs := make(map[string]decimal.Decimal)
+	for _, entry := range strings.Split(os.Getenv("RATE_MAX_CHANGE_PAIRS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pair, value, ok := strings.Cut(entry, "=")
+		limit, err := decimal.NewFromString(strings.TrimSpace(value))
+		if !ok || err != nil || limit.IsNegative() {
+			log.Fatalf("invalid RATE_MAX_CHANGE_PAIRS entry %q", entry)
+		}
+		pairs[strings.ToUpper(strings.TrimSpace(pair))] = limit
+	}
+	return service.GuardrailPolicy{MaxChange: maxChange, PairMaxChange: pairs}
+}
+
+func roundingModeFromEnv() conversion.RoundingMode {
+	mode, err := conversion.ParseRoundingMode(getEnvOrDefault("EXCHANGE_ROUNDING_MODE", string(conversion.RoundHalfUp)))
+	if err != nil {
+		log.Fatalf("invalid EXCHANGE_ROUNDING_MODE: %v", err)
+	}
+	return mode
+}
@@ -2,7 +2,14 @@ CREATE TABLE IF NOT EXISTS currencies (
     id BIGSERIAL PRIMARY KEY,
     code VARCHAR(3) NOT NULL UNIQUE,
     full_name VARCHAR(255) NOT NULL,
-    sign VARCHAR(3) NOT NULL
+    sign VARCHAR(3) NOT NULL,
+    minor_units SMALLINT NOT NULL DEFAULT 2 CHECK (minor_units BETWEEN 0 AND 4),
+    numeric_code VARCHAR(3) NOT NULL DEFAULT '',
+    countries TEXT[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    -- Operator controlled; unlike the ISO 4217 active flag the catalog sync
+    -- never touches it.
+    disabled BOOLEAN NOT NULL DEFAULT FALSE
 );
 
 CREATE TABLE IF NOT EXISTS exchange_rates (
@@ -10,22 +17,51 @@ CREATE TABLE IF NOT EXISTS exchange_rates (
     base_currency_id BIGINT NOT NULL REFERENCES currencies(id) ON DELETE CASCADE,
     target_currency_id BIGINT NOT NULL REFERENCES currencies(id) ON DELETE CASCADE,
     rate NUMERIC(20, 8) NOT NULL,
+    effective_from TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sources TEXT[] NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
     UNIQUE (base_currency_id, target_currency_id)
 );
 
-INSERT INTO currencies (code, full_name, sign) VALUES
-    ('USD', 'US Dollar', '$'),
-    ('EUR', 'Euro', '€'),
-    ('GBP', 'British Pound', '£'),
-    ('JPY', 'Japanese Yen', '¥'),
-    ('CHF', 'Swiss Franc', '₣'),
-    ('CNY', 'Chinese Yuan', '¥'),
-    ('AUD', 'Australian Dollar', 'A$'),
-    ('CAD', 'Canadian Dollar', 'C$'),
-    ('SEK', 'Swedish Krona', 'kr'),
-    ('NOK', 'Norwegian Krone', 'kr'),
-    ('RUB', 'Russian Ruble', '₽'),
-    ('BYN', 'Belarusian Ruble', 'Br')
+-- exchange_rate_id is deliberately not a foreign key: the history of a
+-- deleted rate stays addressable by its id. A deletion is recorded as a
+-- version with deleted set, carrying the last rate and who deleted it.
+CREATE TABLE IF NOT EXISTS exchange_rate_history (
+    id BIGSERIAL PRIMARY KEY,
+    exchange_rate_id BIGINT,
+    base_currency_id BIGINT NOT NULL REFERENCES currencies(id) ON DELETE CASCADE,
+    target_currency_id BIGINT NOT NULL REFERENCES currencies(id) ON DELETE CASCADE,
+    rate NUMERIC(20, 8) NOT NULL,
+    effective_from TIMESTAMPTZ NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    changed_by VARCHAR(100) NOT NULL DEFAULT '',
+    sources TEXT[] NOT NULL DEFAULT '{}',
+    override_reason VARCHAR(500) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS exchange_rate_history_pair_effective_idx
+    ON exchange_rate_history (base_currency_id, target_currency_id, effective_from DESC);
+
+CREATE INDEX IF NOT EXISTS exchange_rate_history_rate_idx
+    ON exchange_rate_history (exchange_rate_id, effective_from DESC);
+
+-- Demo fixtures for the rates below. Reference data for every ISO 4217
+-- currency is loaded by the application: `server catalog seed` inserts the
+-- missing currencies and `server catalog sync` refreshes their metadata.
+INSERT INTO currencies (code, full_name, sign, minor_units, numeric_code, countries) VALUES
+    ('USD', 'US Dollar', '$', 2, '840', ARRAY['US', 'AS', 'EC', 'SV', 'GU', 'MH', 'FM', 'MP', 'PW', 'PR', 'TL', 'TC', 'VG', 'VI', 'BQ', 'IO', 'UM']),
+    ('EUR', 'Euro', '€', 2, '978', ARRAY['AD', 'AT', 'BE', 'CY', 'DE', 'EE', 'ES', 'FI', 'FR', 'GR', 'HR', 'IE', 'IT', 'LT', 'LU', 'LV', 'MC', 'ME', 'MT', 'NL', 'PT', 'SI', 'SK', 'SM', 'VA', 'AX', 'BL', 'GF', 'GP', 'MF', 'MQ', 'PM', 'RE', 'TF', 'YT']),
+    ('GBP', 'British Pound', '£', 2, '826', ARRAY['GB', 'IM', 'JE', 'GG']),
+    ('JPY', 'Japanese Yen', '¥', 0, '392', ARRAY['JP']),
+    ('CHF', 'Swiss Franc', '₣', 2, '756', ARRAY['CH', 'LI']),
+    ('CNY', 'Chinese Yuan', '¥', 2, '156', ARRAY['CN']),
+    ('AUD', 'Australian Dollar', 'A$', 2, '036', ARRAY['AU', 'CX', 'CC', 'HM', 'KI', 'NR', 'NF', 'TV']),
+    ('CAD', 'Canadian Dollar', 'C$', 2, '124', ARRAY['CA']),
+    ('SEK', 'Swedish Krona', 'kr', 2, '752', ARRAY['SE']),
+    ('NOK', 'Norwegian Krone', 'kr', 2, '578', ARRAY['NO', 'SJ', 'BV']),
+    ('RUB', 'Russian Ruble', '₽', 2, '643', ARRAY['RU']),
+    ('BYN', 'Belarusian Ruble', 'Br', 2, '933', ARRAY['BY'])
 ON CONFLICT (code) DO NOTHING;
 
 INSERT INTO exchange_rates (base_currency_id, target_currency_id, rate) VALUES
@@ -36,3 +72,10 @@ INSERT INTO exchange_rates (base_currency_id, target_currency_id, rate) VALUES
     ((SELECT id FROM currencies WHERE code = 'USD'), (SELECT id FROM currencies WHERE code = 'JPY'), 145.30),
     ((SELECT id FROM currencies WHERE code = 'EUR'), (SELECT id FROM currencies WHERE code = 'CHF'), 0.97)
 ON CONFLICT (base_currency_id, target_currency_id) DO NOTHING;
+
+INSERT INTO exchange_rate_history (exchange_rate_id, base_currency_id, target_currency_id, rate, effective_from)
+SELECT er.id, er.base_currency_id, er.target_currency_id, er.rate, er.effective_from
+FROM exchange_rates er
+WHERE NOT EXISTS (
+    SELECT 1 FROM exchange_rate_history h WHERE h.exchange_rate_id = er.id
+);
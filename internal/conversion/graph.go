@@ -0,0 +1,65 @@
+package conversion
+
+import (
+	"currency-exchange/internal/entity"
+	"sort"
+)
+
+const DefaultMaxHops = 4
+
+// Graph is the rate book seen as an undirected currency graph: every stored
+// rate can be walked forward or inverted. Rates that are not positive cannot
+// be converted with and are left out.
+type Graph struct {
+	edges map[int64][]Leg
+}
+
+func NewGraph(rates []entity.ExchangeRate) *Graph {
+	g := &Graph{edges: make(map[int64][]Leg)}
+	for _, rate := range rates {
+		if !rate.Rate.IsPositive() {
+			continue
+		}
+		g.edges[rate.BaseCurrency.ID] = append(g.edges[rate.BaseCurrency.ID], Leg{Rate: rate})
+		g.edges[rate.TargetCurrency.ID] = append(g.edges[rate.TargetCurrency.ID], Leg{Rate: rate, Inverted: true})
+	}
+	return g
+}
+
+// FindPath searches the shortest conversion path from one currency to
+// another using at most maxHops legs. Among paths with the same number of
+// hops it prefers fewer inverted legs and then lower rate ids.
+func (g *Graph) FindPath(fromID int64, toID int64, maxHops int) (Path, bool) {
+	if fromID == toID {
+		return Path{}, true
+	}
+
+	best := map[int64]Path{fromID: {}}
+	frontier := []int64{fromID}
+	for hop := 1; hop <= maxHops && len(frontier) > 0; hop++ {
+		next := make(map[int64]Path)
+		for _, node := range frontier {
+			for _, leg := range g.edges[node] {
+				to := leg.To().ID
+				if _, visited := best[to]; visited {
+					continue
+				}
+				candidate := best[node].extend(leg)
+				if current, ok := next[to]; !ok || candidate.less(current) {
+					next[to] = candidate
+				}
+			}
+		}
+		if path, ok := next[toID]; ok {
+			return path, true
+		}
+
+		frontier = frontier[:0]
+		for id, path := range next {
+			best[id] = path
+			frontier = append(frontier, id)
+		}
+		sort.Slice(frontier, func(i, j int) bool { return frontier[i] < frontier[j] })
+	}
+	return Path{}, false
+}
@@ -0,0 +1,181 @@
+package conversion
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"currency-exchange/internal/entity"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	rub = entity.Currency{ID: 1, Code: "RUB"}
+	usd = entity.Currency{ID: 2, Code: "USD"}
+	eur = entity.Currency{ID: 3, Code: "EUR"}
+	chf = entity.Currency{ID: 4, Code: "CHF"}
+	gbp = entity.Currency{ID: 5, Code: "GBP"}
+)
+
+func rate(id int64, base entity.Currency, target entity.Currency, value string) entity.ExchangeRate {
+	return entity.ExchangeRate{ID: id, BaseCurrency: base, TargetCurrency: target, Rate: decimal.RequireFromString(value)}
+}
+
+// describeLegs renders a path as rate ids, with inverted legs marked "^-1".
+func describeLegs(path Path) []string {
+	legs := make([]string, 0, len(path.Legs))
+	for _, leg := range path.Legs {
+		if leg.Inverted {
+			legs = append(legs, fmt.Sprintf("%d^-1", leg.Rate.ID))
+		} else {
+			legs = append(legs, fmt.Sprint(leg.Rate.ID))
+		}
+	}
+	return legs
+}
+
+func TestFindPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		rates    []entity.ExchangeRate
+		from     entity.Currency
+		to       entity.Currency
+		maxHops  int
+		wantOK   bool
+		wantLegs []string
+	}{
+		{
+			name:     "same currency",
+			from:     usd,
+			to:       usd,
+			maxHops:  DefaultMaxHops,
+			wantOK:   true,
+			wantLegs: []string{},
+		},
+		{
+			name:     "direct rate inverted",
+			rates:    []entity.ExchangeRate{rate(1, usd, eur, "0.92")},
+			from:     eur,
+			to:       usd,
+			maxHops:  DefaultMaxHops,
+			wantOK:   true,
+			wantLegs: []string{"1^-1"},
+		},
+		{
+			name: "shortest path beats a longer one with lower ids",
+			rates: []entity.ExchangeRate{
+				rate(1, usd, gbp, "0.78"),
+				rate(2, gbp, eur, "1.17"),
+				rate(5, usd, eur, "0.92"),
+			},
+			from:     usd,
+			to:       eur,
+			maxHops:  DefaultMaxHops,
+			wantOK:   true,
+			wantLegs: []string{"5"},
+		},
+		{
+			name: "equal length prefers fewer inverted legs",
+			rates: []entity.ExchangeRate{
+				rate(1, usd, eur, "0.92"),
+				rate(2, chf, eur, "1.03"),
+				rate(3, usd, gbp, "0.78"),
+				rate(4, gbp, chf, "1.15"),
+			},
+			from:     usd,
+			to:       chf,
+			maxHops:  DefaultMaxHops,
+			wantOK:   true,
+			wantLegs: []string{"3", "4"},
+		},
+		{
+			name: "equal length and inversions prefers lower rate ids",
+			rates: []entity.ExchangeRate{
+				rate(3, usd, eur, "0.92"),
+				rate(4, eur, chf, "0.97"),
+				rate(1, usd, gbp, "0.78"),
+				rate(6, gbp, chf, "1.15"),
+			},
+			from:     usd,
+			to:       chf,
+			maxHops:  DefaultMaxHops,
+			wantOK:   true,
+			wantLegs: []string{"1", "6"},
+		},
+		{
+			name: "max hops cuts off a longer chain",
+			rates: []entity.ExchangeRate{
+				rate(1, rub, usd, "0.0124"),
+				rate(2, usd, eur, "0.92"),
+				rate(3, eur, chf, "0.97"),
+			},
+			from:    rub,
+			to:      chf,
+			maxHops: 2,
+			wantOK:  false,
+		},
+		{
+			name: "max hops allows a chain of exactly that length",
+			rates: []entity.ExchangeRate{
+				rate(1, rub, usd, "0.0124"),
+				rate(2, usd, eur, "0.92"),
+				rate(3, eur, chf, "0.97"),
+			},
+			from:     rub,
+			to:       chf,
+			maxHops:  3,
+			wantOK:   true,
+			wantLegs: []string{"1", "2", "3"},
+		},
+		{
+			name: "zero rate edges are skipped",
+			rates: []entity.ExchangeRate{
+				rate(1, usd, eur, "0"),
+				rate(2, usd, gbp, "0.78"),
+				rate(3, gbp, eur, "1.17"),
+			},
+			from:     usd,
+			to:       eur,
+			maxHops:  DefaultMaxHops,
+			wantOK:   true,
+			wantLegs: []string{"2", "3"},
+		},
+		{
+			name:    "zero rate leaves the pair unreachable",
+			rates:   []entity.ExchangeRate{rate(1, usd, eur, "0")},
+			from:    eur,
+			to:      usd,
+			maxHops: DefaultMaxHops,
+			wantOK:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, ok := NewGraph(tt.rates).FindPath(tt.from.ID, tt.to.ID, tt.maxHops)
+			if ok != tt.wantOK {
+				t.Fatalf("FindPath() ok = %t, want %t", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if legs := describeLegs(path); !reflect.DeepEqual(legs, tt.wantLegs) {
+				t.Errorf("FindPath() legs = %v, want %v", legs, tt.wantLegs)
+			}
+		})
+	}
+}
+
+func TestPathRate(t *testing.T) {
+	path, ok := NewGraph([]entity.ExchangeRate{
+		rate(1, usd, eur, "0.92"),
+		rate(2, chf, eur, "1.04"),
+	}).FindPath(usd.ID, chf.ID, DefaultMaxHops)
+	if !ok {
+		t.Fatalf("FindPath() found no path")
+	}
+	// 0.92 / 1.04, divided once at the end.
+	if got, want := path.Rate().String(), "0.8846153846153846"; got != want {
+		t.Errorf("Rate() = %s, want %s", got, want)
+	}
+}
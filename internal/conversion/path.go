@@ -0,0 +1,110 @@
+package conversion
+
+import (
+	"currency-exchange/internal/entity"
+
+	"github.com/shopspring/decimal"
+)
+
+// RatePrecision is the number of decimal places kept when a rate has to be
+// inverted.
+const RatePrecision = 16
+
+// Leg is a single step of a conversion path. An inverted leg walks a stored
+// rate from its target currency back to its base currency.
+type Leg struct {
+	Rate     entity.ExchangeRate
+	Inverted bool
+}
+
+func (l Leg) From() entity.Currency {
+	if l.Inverted {
+		return l.Rate.TargetCurrency
+	}
+	return l.Rate.BaseCurrency
+}
+
+func (l Leg) To() entity.Currency {
+	if l.Inverted {
+		return l.Rate.BaseCurrency
+	}
+	return l.Rate.TargetCurrency
+}
+
+// Value is the multiplier this leg applies to an amount in From currency.
+func (l Leg) Value() decimal.Decimal {
+	if l.Inverted {
+		return decimal.NewFromInt(1).DivRound(l.Rate.Rate, RatePrecision)
+	}
+	return l.Rate.Rate
+}
+
+// Path is an ordered chain of legs. An empty path converts a currency into
+// itself.
+type Path struct {
+	Legs []Leg
+}
+
+func (p Path) Hops() int {
+	return len(p.Legs)
+}
+
+// Rate is the composite rate of the whole path. Inverted legs are collected
+// into a single divisor so the result is rounded only once.
+func (p Path) Rate() decimal.Decimal {
+	numerator, denominator := p.Fraction()
+	if denominator.Equal(decimal.NewFromInt(1)) {
+		return numerator
+	}
+	return numerator.DivRound(denominator, RatePrecision)
+}
+
+// Fraction returns the exact composite rate as numerator / denominator: the
+// product of forward legs over the product of inverted legs.
+func (p Path) Fraction() (decimal.Decimal, decimal.Decimal) {
+	numerator := decimal.NewFromInt(1)
+	denominator := decimal.NewFromInt(1)
+	for _, leg := range p.Legs {
+		if leg.Inverted {
+			denominator = denominator.Mul(leg.Rate.Rate)
+		} else {
+			numerator = numerator.Mul(leg.Rate.Rate)
+		}
+	}
+	return numerator, denominator
+}
+
+func (p Path) inverted() int {
+	count := 0
+	for _, leg := range p.Legs {
+		if leg.Inverted {
+			count++
+		}
+	}
+	return count
+}
+
+func (p Path) extend(leg Leg) Path {
+	legs := make([]Leg, 0, len(p.Legs)+1)
+	legs = append(legs, p.Legs...)
+	legs = append(legs, leg)
+	return Path{Legs: legs}
+}
+
+// less orders paths of equal length: fewer inverted legs win because every
+// inversion is a division that loses precision, then lower rate ids win so
+// the choice is stable between calls.
+func (p Path) less(other Path) bool {
+	if p.inverted() != other.inverted() {
+		return p.inverted() < other.inverted()
+	}
+	for i := range p.Legs {
+		if p.Legs[i].Rate.ID != other.Legs[i].Rate.ID {
+			return p.Legs[i].Rate.ID < other.Legs[i].Rate.ID
+		}
+		if p.Legs[i].Inverted != other.Legs[i].Inverted {
+			return !p.Legs[i].Inverted
+		}
+	}
+	return false
+}
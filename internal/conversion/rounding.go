@@ -0,0 +1,45 @@
+package conversion
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+type RoundingMode string
+
+const (
+	// RoundHalfUp rounds halves away from zero. Used for customer facing
+	// quotes.
+	RoundHalfUp RoundingMode = "half_up"
+	// RoundHalfEven rounds halves to the nearest even digit (banker's
+	// rounding).
+	RoundHalfEven RoundingMode = "half_even"
+	// RoundDown truncates towards zero.
+	RoundDown RoundingMode = "down"
+	// RoundUp rounds away from zero.
+	RoundUp RoundingMode = "up"
+)
+
+func ParseRoundingMode(value string) (RoundingMode, error) {
+	switch mode := RoundingMode(value); mode {
+	case RoundHalfUp, RoundHalfEven, RoundDown, RoundUp:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown rounding mode %q, expected one of half_up, half_even, down, up", value)
+	}
+}
+
+// Round rounds amount to the given number of decimal places.
+func Round(amount decimal.Decimal, places int32, mode RoundingMode) decimal.Decimal {
+	switch mode {
+	case RoundHalfEven:
+		return amount.RoundBank(places)
+	case RoundDown:
+		return amount.RoundDown(places)
+	case RoundUp:
+		return amount.RoundUp(places)
+	default:
+		return amount.Round(places)
+	}
+}
@@ -0,0 +1,67 @@
+package conversion
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		amount string
+		places int32
+		want   map[RoundingMode]string
+	}{
+		{
+			amount: "2.345",
+			places: 2,
+			want:   map[RoundingMode]string{RoundHalfUp: "2.35", RoundHalfEven: "2.34", RoundDown: "2.34", RoundUp: "2.35"},
+		},
+		{
+			amount: "2.355",
+			places: 2,
+			want:   map[RoundingMode]string{RoundHalfUp: "2.36", RoundHalfEven: "2.36", RoundDown: "2.35", RoundUp: "2.36"},
+		},
+		{
+			amount: "2.341",
+			places: 2,
+			want:   map[RoundingMode]string{RoundHalfUp: "2.34", RoundHalfEven: "2.34", RoundDown: "2.34", RoundUp: "2.35"},
+		},
+		{
+			amount: "-2.345",
+			places: 2,
+			want:   map[RoundingMode]string{RoundHalfUp: "-2.35", RoundHalfEven: "-2.34", RoundDown: "-2.34", RoundUp: "-2.35"},
+		},
+		{
+			amount: "1234.5",
+			places: 0,
+			want:   map[RoundingMode]string{RoundHalfUp: "1235", RoundHalfEven: "1234", RoundDown: "1234", RoundUp: "1235"},
+		},
+		{
+			amount: "0.1235",
+			places: 3,
+			want:   map[RoundingMode]string{RoundHalfUp: "0.124", RoundHalfEven: "0.124", RoundDown: "0.123", RoundUp: "0.124"},
+		},
+	}
+	for _, tt := range tests {
+		for mode, want := range tt.want {
+			t.Run(tt.amount+"/"+string(mode), func(t *testing.T) {
+				got := Round(decimal.RequireFromString(tt.amount), tt.places, mode)
+				if !got.Equal(decimal.RequireFromString(want)) {
+					t.Errorf("Round(%s, %d, %s) = %s, want %s", tt.amount, tt.places, mode, got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestParseRoundingMode(t *testing.T) {
+	for _, value := range []string{"half_up", "half_even", "down", "up"} {
+		if mode, err := ParseRoundingMode(value); err != nil || string(mode) != value {
+			t.Errorf("ParseRoundingMode(%q) = %q, %v", value, mode, err)
+		}
+	}
+	if _, err := ParseRoundingMode("ceiling"); err == nil {
+		t.Errorf("ParseRoundingMode(%q) error = nil, want an error", "ceiling")
+	}
+}
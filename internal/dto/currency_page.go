@@ -6,3 +6,10 @@ type CurrencyPageDto struct {
 	PageSize   int32         `json:"pageSize"`
 	Total      int           `json:"total"`
 }
+
+type CurrencyCursorPageDto struct {
+	Items    []CurrencyDto `json:"items"`
+	PageSize int32         `json:"pageSize"`
+	Next     string        `json:"next,omitempty"`
+	Prev     string        `json:"prev,omitempty"`
+}
@@ -3,3 +3,10 @@ package dto
 type ErrorDto struct {
 	Message string `json:"message"`
 }
+
+// BatchErrorDto describes why a single item of a batch failed. Kind is one of
+// validation, not_found, conflict, stale, inactive or internal.
+type BatchErrorDto struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
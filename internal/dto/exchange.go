@@ -1,12 +1,22 @@
 package dto
 
-import "github.com/shopspring/decimal"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
 
 type CurrencyDto struct {
-	ID       int64  `json:"id"`
-	Code     string `json:"code"`
-	FullName string `json:"fullName"`
-	Sign     string `json:"sign"`
+	ID          int64    `json:"id"`
+	Code        string   `json:"code"`
+	FullName    string   `json:"fullName"`
+	Sign        string   `json:"sign"`
+	MinorUnits  int32    `json:"minorUnits"`
+	NumericCode string   `json:"numericCode"`
+	Countries   []string `json:"countries"`
+	Active      bool     `json:"active"`
+	Disabled    bool     `json:"disabled"`
 }
 
 type ExchangeRateDto struct {
@@ -14,18 +24,144 @@ type ExchangeRateDto struct {
 	BaseCurrency   CurrencyDto     `json:"baseCurrency"`
 	TargetCurrency CurrencyDto     `json:"targetCurrency"`
 	Rate           decimal.Decimal `json:"rate"`
+	EffectiveFrom  *time.Time      `json:"effectiveFrom,omitempty"`
+	Sources        []string        `json:"sources,omitempty"`
+	UpdatedAt      *time.Time      `json:"updatedAt,omitempty"`
+}
+
+type ExchangeRateVersionDto struct {
+	ID             int64           `json:"id"`
+	ExchangeRateID int64           `json:"exchangeRateId"`
+	BaseCurrency   CurrencyDto     `json:"baseCurrency"`
+	TargetCurrency CurrencyDto     `json:"targetCurrency"`
+	Rate           decimal.Decimal `json:"rate"`
+	EffectiveFrom  time.Time       `json:"effectiveFrom"`
+	RecordedAt     time.Time       `json:"recordedAt"`
+	Deleted        bool            `json:"deleted"`
+	ChangedBy      string          `json:"changedBy,omitempty"`
+	Sources        []string        `json:"sources,omitempty"`
+	OverrideReason string          `json:"overrideReason,omitempty"`
+}
+
+// StaleRateDto is a stored rate older than the freshness policy allows. Age
+// and MaxAge are Go duration strings such as 26h3m0s.
+type StaleRateDto struct {
+	Rate   ExchangeRateDto `json:"rate"`
+	Age    string          `json:"age"`
+	MaxAge string          `json:"maxAge"`
+}
+
+// ConversionLegDto is one stored rate used by a conversion. Base and Target
+// follow the direction of the conversion, so for an inverted leg they are
+// swapped relative to the stored rate.
+type ConversionLegDto struct {
+	RateID     int64           `json:"rateId"`
+	Base       string          `json:"base"`
+	Target     string          `json:"target"`
+	StoredRate decimal.Decimal `json:"storedRate"`
+	Rate       decimal.Decimal `json:"rate"`
+	Inverted   bool            `json:"inverted"`
+	UpdatedAt  *time.Time      `json:"updatedAt,omitempty"`
+}
+
+type ConversionPathDto struct {
+	Legs []ConversionLegDto `json:"legs"`
+	Rate decimal.Decimal    `json:"rate"`
 }
 
+const (
+	ExchangeModeForward = "forward"
+	ExchangeModeReverse = "reverse"
+)
+
+// ExchangeDto is the result of a conversion. ConvertAmount is rounded to the
+// minor units of the target currency with RoundingMode; RawConvertAmount is
+// the exact product before rounding.
 type ExchangeDto struct {
-	ExchangeRate  ExchangeRateDto `json:"exchangeRate"`
-	Amount        decimal.Decimal `json:"amount"`
-	ConvertAmount decimal.Decimal `json:"convertAmount"`
+	ExchangeRate     ExchangeRateDto   `json:"exchangeRate"`
+	Mode             string            `json:"mode"`
+	Amount           decimal.Decimal   `json:"amount"`
+	ConvertAmount    decimal.Decimal   `json:"convertAmount"`
+	RawConvertAmount decimal.Decimal   `json:"rawConvertAmount"`
+	RoundingMode     string            `json:"roundingMode"`
+	Path             ConversionPathDto `json:"path"`
+	AsOf             *time.Time        `json:"asOf,omitempty"`
+	// Stale is set when a rate on the path is older than the freshness
+	// policy allows and the policy only warns.
+	Stale bool `json:"stale,omitempty"`
 }
 
+// CreateCurrencyRequest creates a currency. The code must be an ISO 4217
+// code unless Custom is set. Omitted metadata is taken from the ISO 4217
+// catalog; for custom codes MinorUnits defaults to 2 and Active to true.
+// Disabled defaults to false.
 type CreateCurrencyRequest struct {
-	Code     string `json:"code"`
-	FullName string `json:"fullName"`
-	Sign     string `json:"sign"`
+	Code        string   `json:"code"`
+	FullName    string   `json:"fullName"`
+	Sign        string   `json:"sign"`
+	MinorUnits  *int32   `json:"minorUnits,omitempty"`
+	NumericCode string   `json:"numericCode,omitempty"`
+	Countries   []string `json:"countries,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+	Disabled    *bool    `json:"disabled,omitempty"`
+	Custom      bool     `json:"custom,omitempty"`
+}
+
+const (
+	CurrencyImportCreated = "created"
+	CurrencyImportSkipped = "skipped"
+	CurrencyImportInvalid = "invalid"
+)
+
+// CurrencyImportRowDto reports the outcome of one row of a currency import.
+// Line is the 1-based position in the input.
+type CurrencyImportRowDto struct {
+	Line   int            `json:"line"`
+	Code   string         `json:"code"`
+	Status string         `json:"status"`
+	Error  *BatchErrorDto `json:"error,omitempty"`
+}
+
+// CurrencyImportDto is the report of a currency import. Applied is false on a
+// dry run and whenever any row is invalid, in which case nothing was written.
+type CurrencyImportDto struct {
+	DryRun  bool                   `json:"dryRun"`
+	Applied bool                   `json:"applied"`
+	Created int                    `json:"created"`
+	Skipped int                    `json:"skipped"`
+	Invalid int                    `json:"invalid"`
+	Rows    []CurrencyImportRowDto `json:"rows"`
+}
+
+// UpdateCurrencyRequest replaces every attribute of a currency. Omitted
+// metadata falls back to the same defaults as on creation; an omitted
+// Disabled keeps the current state.
+type UpdateCurrencyRequest struct {
+	FullName    string   `json:"fullName"`
+	Sign        string   `json:"sign"`
+	MinorUnits  *int32   `json:"minorUnits,omitempty"`
+	NumericCode string   `json:"numericCode,omitempty"`
+	Countries   []string `json:"countries,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+	Disabled    *bool    `json:"disabled,omitempty"`
+}
+
+// PatchCurrencyRequest changes only the attributes that are present.
+type PatchCurrencyRequest struct {
+	FullName    *string   `json:"fullName,omitempty"`
+	Sign        *string   `json:"sign,omitempty"`
+	MinorUnits  *int32    `json:"minorUnits,omitempty"`
+	NumericCode *string   `json:"numericCode,omitempty"`
+	Countries   *[]string `json:"countries,omitempty"`
+	Active      *bool     `json:"active,omitempty"`
+	Disabled    *bool     `json:"disabled,omitempty"`
+}
+
+type CatalogSyncDto struct {
+	Mode      string   `json:"mode"`
+	Inserted  []string `json:"inserted"`
+	Updated   []string `json:"updated"`
+	Unchanged int      `json:"unchanged"`
 }
 
 type CreateRateRequest struct {
@@ -34,8 +170,118 @@ type CreateRateRequest struct {
 	Rate       decimal.Decimal `json:"rate"`
 }
 
+// PutRateRequest sets the rate of the pair addressed by the URL.
+type PutRateRequest struct {
+	Rate decimal.Decimal `json:"rate"`
+}
+
 type UpdateRateRequest struct {
 	BaseCode   string          `json:"baseCode"`
 	TargetCode string          `json:"targetCode"`
 	Rate       decimal.Decimal `json:"rate"`
 }
+
+type ExchangeBatchItemRequest struct {
+	Ref    string          `json:"ref"`
+	Base   string          `json:"base"`
+	Target string          `json:"target"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+type ExchangeBatchRequest struct {
+	Items []ExchangeBatchItemRequest `json:"items"`
+}
+
+type ExchangeBatchItemDto struct {
+	Ref    string         `json:"ref"`
+	Result *ExchangeDto   `json:"result,omitempty"`
+	Error  *BatchErrorDto `json:"error,omitempty"`
+}
+
+type ExchangeBatchDto struct {
+	Items     []ExchangeBatchItemDto `json:"items"`
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+}
+
+type ExchangeAllItemDto struct {
+	TargetCurrency   CurrencyDto     `json:"targetCurrency"`
+	Rate             decimal.Decimal `json:"rate"`
+	ConvertAmount    decimal.Decimal `json:"convertAmount"`
+	RawConvertAmount decimal.Decimal `json:"rawConvertAmount"`
+	Stale            bool            `json:"stale,omitempty"`
+}
+
+// ExchangeAllDto lists the conversions into every reachable currency.
+// StaleTargets holds the currencies left out because the freshness policy
+// rejects the rates that reach them.
+type ExchangeAllDto struct {
+	BaseCurrency CurrencyDto          `json:"baseCurrency"`
+	Amount       decimal.Decimal      `json:"amount"`
+	Items        []ExchangeAllItemDto `json:"items"`
+	Unreachable  []string             `json:"unreachable"`
+	StaleTargets []string             `json:"staleTargets,omitempty"`
+	RoundingMode string               `json:"roundingMode"`
+	AsOf         *time.Time           `json:"asOf,omitempty"`
+}
+
+// RateMatrixCellDto is the cross rate from the row currency to Target. Rate
+// is null and Reachable is false when no path exists. Stale marks a rate
+// relying on a stale leg, or, under the reject policy, a pair reachable only
+// over stale rates.
+type RateMatrixCellDto struct {
+	Target    string           `json:"target"`
+	Rate      *decimal.Decimal `json:"rate"`
+	Reachable bool             `json:"reachable"`
+	Stale     bool             `json:"stale"`
+}
+
+type RateMatrixRowDto struct {
+	Base  string              `json:"base"`
+	Rates []RateMatrixCellDto `json:"rates"`
+}
+
+type RateMatrixDto struct {
+	Codes []string           `json:"codes"`
+	Rows  []RateMatrixRowDto `json:"rows"`
+	AsOf  *time.Time         `json:"asOf,omitempty"`
+}
+
+// RateImportItemRequest is one row of a JSON rate import. The rate may be a
+// JSON number or a numeric string.
+type RateImportItemRequest struct {
+	Base   string      `json:"base"`
+	Target string      `json:"target"`
+	Rate   json.Number `json:"rate"`
+}
+
+const (
+	RateImportNew       = "new"
+	RateImportChanged   = "changed"
+	RateImportUnchanged = "unchanged"
+	RateImportInvalid   = "invalid"
+)
+
+// RateImportRowDto reports what an import did, or would do on a dry run,
+// with one row of the input. Line is the 1-based position in the input.
+type RateImportRowDto struct {
+	Line         int              `json:"line"`
+	Base         string           `json:"base"`
+	Target       string           `json:"target"`
+	Rate         *decimal.Decimal `json:"rate,omitempty"`
+	PreviousRate *decimal.Decimal `json:"previousRate,omitempty"`
+	Status       string           `json:"status"`
+	Error        *BatchErrorDto   `json:"error,omitempty"`
+}
+
+// RateImportDto is the report of a rate import. Applied is false on a dry run
+// and whenever any row is invalid, in which case nothing was written.
+type RateImportDto struct {
+	DryRun    bool               `json:"dryRun"`
+	Applied   bool               `json:"applied"`
+	New       int                `json:"new"`
+	Changed   int                `json:"changed"`
+	Unchanged int                `json:"unchanged"`
+	Invalid   int                `json:"invalid"`
+	Rows      []RateImportRowDto `json:"rows"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+// ProviderStatusDto reports the polling state of a rate provider. Failures
+// counts consecutive failed polls and is reset by a successful one.
+type ProviderStatusDto struct {
+	Name        string     `json:"name"`
+	Base        string     `json:"base"`
+	Interval    string     `json:"interval"`
+	LastRun     *time.Time `json:"lastRun,omitempty"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+	Failures    int        `json:"failures"`
+	NextRun     *time.Time `json:"nextRun,omitempty"`
+	// Written, Skipped and Guarded describe the last successful poll.
+	// Guarded lists the pairs held back by the rate change guardrail.
+	Written int      `json:"written"`
+	Skipped []string `json:"skipped"`
+	Guarded []string `json:"guarded"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+type ExchangeRatePageDto struct {
+	Items      []ExchangeRateDto `json:"items"`
+	PageNumber int32             `json:"pageNumber"`
+	PageSize   int32             `json:"pageSize"`
+	Total      int               `json:"total"`
+}
+
+type ExchangeRateCursorPageDto struct {
+	Items    []ExchangeRateDto `json:"items"`
+	PageSize int32             `json:"pageSize"`
+	Next     string            `json:"next,omitempty"`
+	Prev     string            `json:"prev,omitempty"`
+}
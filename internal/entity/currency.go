@@ -5,11 +5,28 @@ type Currency struct {
 	Code     string `db:"code"`
 	FullName string `db:"name"`
 	Sign     string `db:"sign"`
+	// MinorUnits is the ISO 4217 exponent: the number of decimal places of
+	// the smallest unit of the currency (2 for USD, 0 for JPY).
+	MinorUnits int32 `db:"minor_units"`
+	// NumericCode is the three digit ISO 4217 code, empty for private codes.
+	NumericCode string `db:"numeric_code"`
+	// Countries lists the ISO 3166-1 alpha-2 codes of countries using the
+	// currency.
+	Countries []string `db:"countries"`
+	// Active is false for currencies withdrawn from circulation.
+	Active bool `db:"active"`
+	// Disabled is set by operators to hide a currency from listings and
+	// conversions. Unlike Active it is never changed by the catalog sync.
+	Disabled bool `db:"disabled"`
 }
 
 const (
-	CurrencyCodeMaxLen     = 3
-	CurrencySignMaxLen     = 3
-	CurrencyFullNameMinLen = 3
-	CurrencyFullNameMaxLen = 40
+	CurrencyCodeMaxLen        = 3
+	CurrencySignMaxLen        = 3
+	CurrencyFullNameMinLen    = 3
+	CurrencyFullNameMaxLen    = 40
+	CurrencyDefaultMinorUnits = 2
+	CurrencyMaxMinorUnits     = 4
+	CurrencyNumericCodeLen    = 3
+	CountryCodeLen            = 2
 )
@@ -1,6 +1,8 @@
 package entity
 
 import (
+	"time"
+
 	"github.com/shopspring/decimal"
 )
 
@@ -9,9 +11,50 @@ type ExchangeRate struct {
 	BaseCurrency   Currency        `db:"base_currency"`
 	TargetCurrency Currency        `db:"target_currency"`
 	Rate           decimal.Decimal `db:"rate"`
+	EffectiveFrom  time.Time       `db:"effective_from"`
+	// Sources names the rate providers whose quotes produced the rate; it is
+	// empty for rates entered by hand.
+	Sources []string `db:"sources"`
+	// UpdatedAt is when the rate was last written or confirmed unchanged by
+	// a provider; freshness is judged by it.
+	UpdatedAt time.Time `db:"updated_at"`
+	// ChangedBy and OverrideReason describe the write itself: who made it
+	// and why it was allowed past the rate change guardrail. They are
+	// recorded only in the rate history.
+	ChangedBy      string `db:"-"`
+	OverrideReason string `db:"-"`
+}
+
+// ExchangeRateVersion is one immutable entry of the rate history. Every
+// create or update of an exchange rate appends a new version; a deletion
+// appends a version with Deleted set that keeps the last rate.
+type ExchangeRateVersion struct {
+	ID             int64           `db:"id"`
+	ExchangeRateID int64           `db:"exchange_rate_id"`
+	BaseCurrency   Currency        `db:"base_currency"`
+	TargetCurrency Currency        `db:"target_currency"`
+	Rate           decimal.Decimal `db:"rate"`
+	EffectiveFrom  time.Time       `db:"effective_from"`
+	RecordedAt     time.Time       `db:"recorded_at"`
+	Deleted        bool            `db:"deleted"`
+	ChangedBy      string          `db:"changed_by"`
+	Sources        []string        `db:"sources"`
+	OverrideReason string          `db:"override_reason"`
 }
 
+const (
+	// ExchangeRateMaxActorLen limits the recorded name of whoever changed a
+	// rate.
+	ExchangeRateMaxActorLen = 100
+	// ExchangeRateMaxOverrideReasonLen limits the recorded reason of a
+	// guardrail override.
+	ExchangeRateMaxOverrideReasonLen = 500
+)
+
 const (
 	ExchangeRateMaxScale   = 6
 	ExchangeAmountMaxScale = 6
+	// ExchangeRateMaxIntegerDigits is what the NUMERIC(20, 8) rate column
+	// holds before the decimal point.
+	ExchangeRateMaxIntegerDigits = 12
 )
@@ -1,6 +1,7 @@
 package error
 
 import (
+	"errors"
 	"fmt"
 )
 
@@ -55,10 +56,68 @@ func (e *InternalError) Is(target error) bool {
 	return ok
 }
 
+type ConflictError struct {
+	Message string
+	Detail  string
+}
+
+func (e *ConflictError) Error() string {
+	if e.Detail == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Message, e.Detail)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	_, ok := target.(*ConflictError)
+	return ok
+}
+
+// StaleError reports that a request would rely on a rate older than the
+// freshness policy allows.
+type StaleError struct {
+	Message string
+	Detail  string
+}
+
+func (e *StaleError) Error() string {
+	if e.Detail == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Message, e.Detail)
+}
+
+func (e *StaleError) Is(target error) bool {
+	_, ok := target.(*StaleError)
+	return ok
+}
+
+// InactiveError reports that a request names a currency an operator has
+// deactivated.
+type InactiveError struct {
+	Message string
+	Detail  string
+}
+
+func (e *InactiveError) Error() string {
+	if e.Detail == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Message, e.Detail)
+}
+
+func (e *InactiveError) Is(target error) bool {
+	_, ok := target.(*InactiveError)
+	return ok
+}
+
 var (
 	ErrNotFound   = &NotFoundError{}
 	ErrValidation = &ValidationError{}
 	ErrInternal   = &InternalError{}
+	ErrConflict   = &ConflictError{}
+	ErrStale      = &StaleError{}
+	ErrInactive   = &InactiveError{}
 )
 
 func NotFound(message string, detail string) error {
@@ -69,6 +128,75 @@ func Validation(message string, detail string) error {
 	return &ValidationError{Message: message, Detail: detail}
 }
 
+func Conflict(message string, detail string) error {
+	return &ConflictError{Message: message, Detail: detail}
+}
+
+func Stale(message string, detail string) error {
+	return &StaleError{Message: message, Detail: detail}
+}
+
+func Inactive(message string, detail string) error {
+	return &InactiveError{Message: message, Detail: detail}
+}
+
 func Internal(message string, detail string) error {
 	return &InternalError{Message: message, Detail: detail}
 }
+
+const (
+	KindValidation = "validation"
+	KindNotFound   = "not_found"
+	KindConflict   = "conflict"
+	KindStale      = "stale"
+	KindInactive   = "inactive"
+	KindInternal   = "internal"
+)
+
+// KindOf names the kind of an application error for clients that receive
+// several errors in one response.
+func KindOf(err error) string {
+	switch {
+	case errors.Is(err, ErrValidation):
+		return KindValidation
+	case errors.Is(err, ErrNotFound):
+		return KindNotFound
+	case errors.Is(err, ErrConflict):
+		return KindConflict
+	case errors.Is(err, ErrStale):
+		return KindStale
+	case errors.Is(err, ErrInactive):
+		return KindInactive
+	default:
+		return KindInternal
+	}
+}
+
+// MessageOf returns the client facing message of an application error.
+func MessageOf(err error) string {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr.Message
+	}
+	var notFoundErr *NotFoundError
+	if errors.As(err, &notFoundErr) {
+		return notFoundErr.Message
+	}
+	var conflictErr *ConflictError
+	if errors.As(err, &conflictErr) {
+		return conflictErr.Message
+	}
+	var staleErr *StaleError
+	if errors.As(err, &staleErr) {
+		return staleErr.Message
+	}
+	var inactiveErr *InactiveError
+	if errors.As(err, &inactiveErr) {
+		return inactiveErr.Message
+	}
+	var internalErr *InternalError
+	if errors.As(err, &internalErr) {
+		return internalErr.Message
+	}
+	return "internal error"
+}
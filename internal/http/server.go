@@ -1,38 +1,70 @@
 package http
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"currency-exchange/internal/conversion"
 	"currency-exchange/internal/dto"
 	apperror "currency-exchange/internal/error"
 	"currency-exchange/internal/pagination"
+	"currency-exchange/internal/provider"
+	"currency-exchange/internal/repository"
 	"currency-exchange/internal/service"
 
 	"github.com/shopspring/decimal"
 )
 
+const (
+	defaultPageSize = 20
+	// actorHeader names who makes a change; it is recorded in audit trails.
+	actorHeader = "X-Actor"
+	// importMaxBodyBytes bounds an import body while it is read, well above
+	// what the row limits of the rate and currency imports need.
+	importMaxBodyBytes = 8 << 20
+)
+
 type CurrencyServer struct {
 	currencyService *service.CurrencyService
 	exchangeService *service.ExchangeService
+	scheduler       *provider.Scheduler
 	mux             *http.ServeMux
 }
 
-func New(currencyService *service.CurrencyService, exchangeService *service.ExchangeService) http.Handler {
+func New(
+	currencyService *service.CurrencyService,
+	exchangeService *service.ExchangeService,
+	scheduler *provider.Scheduler,
+) http.Handler {
 	s := &CurrencyServer{
 		currencyService: currencyService,
 		exchangeService: exchangeService,
+		scheduler:       scheduler,
 		mux:             http.NewServeMux(),
 	}
 
 	s.mux.HandleFunc("/currencies", s.handleCurrencies)
 	s.mux.HandleFunc("/currencies/", s.handleCurrencyByCode)
+	s.mux.HandleFunc("/currencies/import", s.handleCurrencyImport)
+	s.mux.HandleFunc("/currencies/export", s.handleCurrencyExport)
+	s.mux.HandleFunc("/admin/currencies/catalog", s.handleCurrencyCatalog)
+	s.mux.HandleFunc("/admin/providers", s.handleProviders)
 	s.mux.HandleFunc("/rates", s.handleRates)
-	s.mux.HandleFunc("/rates/", s.handleRateByID)
+	s.mux.HandleFunc("/rates/", s.handleRateResource)
+	s.mux.HandleFunc("/rates/matrix", s.handleRateMatrix)
+	s.mux.HandleFunc("/rates/stale", s.handleStaleRates)
+	s.mux.HandleFunc("/rates/import", s.handleRateImport)
 	s.mux.HandleFunc("/exchange", s.handleExchange)
+	s.mux.HandleFunc("/exchange/batch", s.handleExchangeBatch)
+	s.mux.HandleFunc("/exchange/all", s.handleExchangeAll)
 
 	return s
 }
@@ -53,24 +85,64 @@ func (s *CurrencyServer) handleCurrencies(w http.ResponseWriter, r *http.Request
 }
 
 // @Summary List currencies
+// @Description Page number mode by default; passing cursor (empty for the first page) switches to keyset pagination with next/prev cursors and Link headers.
 // @Tags currencies
 // @Accept json
 // @Produce json
 // @Param pageNumber query int false "Page number" minimum(1)
 // @Param pageSize query int false "Page size" minimum(1)
+// @Param cursor query string false "Opaque cursor from a previous next/prev; empty for the first page"
+// @Param search query string false "Code prefix or full name substring, case-insensitive"
+// @Param codes query string false "Comma separated currency codes"
+// @Param active query string false "Filter by ISO 4217 circulation state" Enums(true, false, all) default(all)
+// @Param disabled query string false "Filter by operator state" Enums(true, false, all) default(false)
+// @Param sort query string false "Sort field" Enums(id, code, name) default(id)
+// @Param order query string false "Sort direction" Enums(asc, desc) default(asc)
 // @Success 200 {object} dto.CurrencyPageDto
+// @Success 200 {object} dto.CurrencyCursorPageDto
 // @Failure 400 {object} dto.ErrorDto
 // @Failure 500 {object} dto.ErrorDto
 // @Router /currencies [get]
 func (s *CurrencyServer) handleCurrenciesGet(w http.ResponseWriter, r *http.Request) {
+	direction, err := pagination.ParseSortDirection(r.URL.Query().Get("order"))
+	if err != nil {
+		writeError(w, apperror.Validation("invalid sort direction", err.Error()))
+		return
+	}
+	filter, err := parseCurrencyFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if r.URL.Query().Has("cursor") {
+		request, err := parseCursorRequest(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		request.SortBy = r.URL.Query().Get("sort")
+		request.Direction = direction
+		page, err := s.currencyService.GetCurrencyCursorPage(filter, request)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		setLinkHeader(w, r, page.Next, page.Prev)
+		writeJSON(w, http.StatusOK, page)
+		return
+	}
+
 	pageNumber, pageSize, err := parsePageRequest(r)
 	if err != nil {
 		writeError(w, apperror.Validation("invalid pagination", err.Error()))
 		return
 	}
-	page, err := s.currencyService.GetAllCurrencyPage(pagination.PageRequest{
+	page, err := s.currencyService.GetAllCurrencyPage(filter, pagination.PageRequest{
 		PageNumber: pageNumber,
 		PageSize:   pageSize,
+		SortBy:     r.URL.Query().Get("sort"),
+		Direction:  direction,
 	})
 	if err != nil {
 		writeError(w, err)
@@ -94,7 +166,7 @@ func (s *CurrencyServer) handleCurrenciesPost(w http.ResponseWriter, r *http.Req
 		writeError(w, apperror.Validation("invalid request", err.Error()))
 		return
 	}
-	currency, err := s.currencyService.CreateCurrency(req.Code, req.FullName, req.Sign)
+	currency, err := s.currencyService.CreateCurrency(req)
 	if err != nil {
 		writeError(w, err)
 		return
@@ -102,6 +174,27 @@ func (s *CurrencyServer) handleCurrenciesPost(w http.ResponseWriter, r *http.Req
 	writeJSON(w, http.StatusCreated, currency)
 }
 
+func (s *CurrencyServer) handleCurrencyByCode(w http.ResponseWriter, r *http.Request) {
+	code := strings.TrimPrefix(r.URL.Path, "/currencies/")
+	if code == "" {
+		writeError(w, apperror.Validation("currency code is required", "empty code"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.handleCurrencyByCodeGet(w, r, code)
+	case http.MethodPut:
+		s.handleCurrencyByCodePut(w, r, code)
+	case http.MethodPatch:
+		s.handleCurrencyByCodePatch(w, r, code)
+	case http.MethodDelete:
+		s.handleCurrencyByCodeDelete(w, r, code)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 // @Summary Get currency by code
 // @Tags currencies
 // @Accept json
@@ -112,26 +205,207 @@ func (s *CurrencyServer) handleCurrenciesPost(w http.ResponseWriter, r *http.Req
 // @Failure 404 {object} dto.ErrorDto
 // @Failure 500 {object} dto.ErrorDto
 // @Router /currencies/{code} [get]
-func (s *CurrencyServer) handleCurrencyByCode(w http.ResponseWriter, r *http.Request) {
+func (s *CurrencyServer) handleCurrencyByCodeGet(w http.ResponseWriter, r *http.Request, code string) {
+	currency, err := s.currencyService.GetCurrencyByCode(code)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, currency)
+}
+
+// @Summary Replace currency
+// @Tags currencies
+// @Accept json
+// @Produce json
+// @Param code path string true "Currency code"
+// @Param request body dto.UpdateCurrencyRequest true "Currency payload"
+// @Success 200 {object} dto.CurrencyDto
+// @Failure 400 {object} dto.ErrorDto
+// @Failure 404 {object} dto.ErrorDto
+// @Failure 500 {object} dto.ErrorDto
+// @Router /currencies/{code} [put]
+func (s *CurrencyServer) handleCurrencyByCodePut(w http.ResponseWriter, r *http.Request, code string) {
+	var req dto.UpdateCurrencyRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, apperror.Validation("invalid request", err.Error()))
+		return
+	}
+	currency, err := s.currencyService.ReplaceCurrency(code, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, currency)
+}
+
+// @Summary Partially update currency
+// @Tags currencies
+// @Accept json
+// @Produce json
+// @Param code path string true "Currency code"
+// @Param request body dto.PatchCurrencyRequest true "Fields to change"
+// @Success 200 {object} dto.CurrencyDto
+// @Failure 400 {object} dto.ErrorDto
+// @Failure 404 {object} dto.ErrorDto
+// @Failure 500 {object} dto.ErrorDto
+// @Router /currencies/{code} [patch]
+func (s *CurrencyServer) handleCurrencyByCodePatch(w http.ResponseWriter, r *http.Request, code string) {
+	var req dto.PatchCurrencyRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, apperror.Validation("invalid request", err.Error()))
+		return
+	}
+	currency, err := s.currencyService.PatchCurrency(code, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, currency)
+}
+
+// @Summary Delete currency
+// @Tags currencies
+// @Accept json
+// @Produce json
+// @Param code path string true "Currency code"
+// @Param force query bool false "Also delete the rates and rate history that reference the currency"
+// @Success 204
+// @Failure 400 {object} dto.ErrorDto
+// @Failure 404 {object} dto.ErrorDto
+// @Failure 409 {object} dto.ErrorDto
+// @Failure 500 {object} dto.ErrorDto
+// @Router /currencies/{code} [delete]
+func (s *CurrencyServer) handleCurrencyByCodeDelete(w http.ResponseWriter, r *http.Request, code string) {
+	force, err := parseBoolParam(r, "force")
+	if err != nil {
+		writeError(w, apperror.Validation("invalid force flag", err.Error()))
+		return
+	}
+	if err := s.currencyService.DeleteCurrency(code, force); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Import currencies
+// @Description Validates every row with the rules of POST /currencies and creates them all in one transaction. Nothing is written when any row is invalid or dryRun is set; the report lists every row either way.
+// @Tags currencies
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param format query string false "Input format; defaults from Content-Type" Enums(csv, json)
+// @Param onExisting query string false "Skip codes that already exist or report them as invalid" Enums(skip, fail) default(fail)
+// @Param dryRun query bool false "Only report what would be created"
+// @Param request body []dto.CreateCurrencyRequest true "Currencies; CSV needs a header with at least code,full_name,sign"
+// @Success 200 {object} dto.CurrencyImportDto
+// @Failure 400 {object} dto.ErrorDto
+// @Failure 409 {object} dto.ErrorDto "With onExisting=fail, a code was created while the import ran"
+// @Failure 500 {object} dto.ErrorDto
+// @Router /currencies/import [post]
+func (s *CurrencyServer) handleCurrencyImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	dryRun, err := parseBoolParam(r, "dryRun")
+	if err != nil {
+		writeError(w, apperror.Validation("invalid dryRun flag", err.Error()))
+		return
+	}
+	onExisting := r.URL.Query().Get("onExisting")
+	if onExisting == "" {
+		onExisting = service.ImportFailExisting
+	}
+	body := newImportBody(w, r)
+	result, err := s.currencyService.ImportCurrencies(importFormat(r), body, onExisting, dryRun)
+	if err != nil {
+		writeError(w, body.wrapError(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// @Summary Export currencies
+// @Description Every currency, withdrawn and disabled ones included, in the format accepted by POST /currencies/import.
+// @Tags currencies
+// @Produce json
+// @Produce text/csv
+// @Param format query string false "Output format" Enums(csv, json) default(json)
+// @Success 200 {array} dto.CreateCurrencyRequest
+// @Failure 400 {object} dto.ErrorDto
+// @Failure 500 {object} dto.ErrorDto
+// @Router /currencies/export [get]
+func (s *CurrencyServer) handleCurrencyExport(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	code := strings.TrimPrefix(r.URL.Path, "/currencies/")
-	if code == "" {
-		writeError(w, apperror.Validation("currency code is required", "empty code"))
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = service.ImportFormatJSON
+	}
+	// Buffer the export so a failure can still be reported as an error response.
+	var body bytes.Buffer
+	if err := s.currencyService.ExportCurrencies(format, &body); err != nil {
+		writeError(w, err)
 		return
 	}
-	currency, err := s.currencyService.GetCurrencyByCode(code)
+	contentType := "application/json"
+	if format == service.ImportFormatCSV {
+		contentType = "text/csv"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="currencies.`+format+`"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body.Bytes())
+}
+
+// @Summary Rate provider status
+// @Description Last run, last success, last error and next run of every scheduled rate provider.
+// @Tags admin
+// @Produce json
+// @Success 200 {array} dto.ProviderStatusDto
+// @Router /admin/providers [get]
+func (s *CurrencyServer) handleProviders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.scheduler.Statuses())
+}
+
+// @Summary Seed or sync currencies from the ISO 4217 catalog
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param mode query string false "seed inserts missing currencies, sync also refreshes ISO metadata" Enums(seed, sync) default(seed)
+// @Success 200 {object} dto.CatalogSyncDto
+// @Failure 400 {object} dto.ErrorDto
+// @Failure 500 {object} dto.ErrorDto
+// @Router /admin/currencies/catalog [post]
+func (s *CurrencyServer) handleCurrencyCatalog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = service.CatalogSeed
+	}
+	result, err := s.currencyService.SyncCatalog(mode)
 	if err != nil {
 		writeError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, currency)
+	writeJSON(w, http.StatusOK, result)
 }
 
 func (s *CurrencyServer) handleRates(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
+	case http.MethodGet:
+		s.handleRatesGet(w, r)
 	case http.MethodPost:
 		s.handleRatesPost(w, r)
 	default:
@@ -139,14 +413,88 @@ func (s *CurrencyServer) handleRates(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// @Summary List exchange rates
+// @Description Page number mode by default; passing cursor (empty for the first page) switches to keyset pagination with next/prev cursors and Link headers.
+// @Tags rates
+// @Accept json
+// @Produce json
+// @Param pageNumber query int false "Page number" minimum(1)
+// @Param pageSize query int false "Page size" minimum(1)
+// @Param cursor query string false "Opaque cursor from a previous next/prev; empty for the first page"
+// @Param base query string false "Base currency code"
+// @Param target query string false "Target currency code"
+// @Param currency query string false "Currency code on either side of the pair"
+// @Param updatedSince query string false "Rates written or confirmed at or after this RFC 3339 timestamp or YYYY-MM-DD day (UTC)"
+// @Param order query string false "Sort direction by id" Enums(asc, desc) default(asc)
+// @Success 200 {object} dto.ExchangeRatePageDto
+// @Success 200 {object} dto.ExchangeRateCursorPageDto
+// @Failure 400 {object} dto.ErrorDto
+// @Failure 500 {object} dto.ErrorDto
+// @Router /rates [get]
+func (s *CurrencyServer) handleRatesGet(w http.ResponseWriter, r *http.Request) {
+	direction, err := pagination.ParseSortDirection(r.URL.Query().Get("order"))
+	if err != nil {
+		writeError(w, apperror.Validation("invalid sort direction", err.Error()))
+		return
+	}
+	updatedSince, err := parseSince(r.URL.Query().Get("updatedSince"))
+	if err != nil {
+		writeError(w, apperror.Validation("invalid updatedSince", err.Error()))
+		return
+	}
+	filter := repository.RateFilter{
+		BaseCode:     r.URL.Query().Get("base"),
+		TargetCode:   r.URL.Query().Get("target"),
+		CurrencyCode: r.URL.Query().Get("currency"),
+		UpdatedSince: updatedSince,
+	}
+
+	if r.URL.Query().Has("cursor") {
+		request, err := parseCursorRequest(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		request.Direction = direction
+		page, err := s.exchangeService.GetRateCursorPage(filter, request)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		setLinkHeader(w, r, page.Next, page.Prev)
+		writeJSON(w, http.StatusOK, page)
+		return
+	}
+
+	pageNumber, pageSize, err := parsePageRequest(r)
+	if err != nil {
+		writeError(w, apperror.Validation("invalid pagination", err.Error()))
+		return
+	}
+	page, err := s.exchangeService.GetRatePage(filter, pagination.PageRequest{
+		PageNumber: pageNumber,
+		PageSize:   pageSize,
+		Direction:  direction,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
 // @Summary Create exchange rate
 // @Tags rates
 // @Accept json
 // @Produce json
 // @Param request body dto.CreateRateRequest true "Rate payload"
+// @Param X-Actor header string false "Who changes the rate; recorded in the rate history"
+// @Param override query bool false "Apply rates beyond the change guardrail"
+// @Param overrideReason query string false "Why the guardrail is overridden; required with override"
 // @Success 201 {object} dto.ExchangeRateDto
 // @Failure 400 {object} dto.ErrorDto
 // @Failure 404 {object} dto.ErrorDto
+// @Failure 409 {object} dto.ErrorDto
 // @Failure 500 {object} dto.ErrorDto
 // @Router /rates [post]
 func (s *CurrencyServer) handleRatesPost(w http.ResponseWriter, r *http.Request) {
@@ -155,7 +503,12 @@ func (s *CurrencyServer) handleRatesPost(w http.ResponseWriter, r *http.Request)
 		writeError(w, apperror.Validation("invalid request", err.Error()))
 		return
 	}
-	rate, err := s.exchangeService.CreateRate(req.BaseCode, req.TargetCode, req.Rate)
+	options, err := parseRateWriteOptions(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rate, err := s.exchangeService.CreateRate(req.BaseCode, req.TargetCode, req.Rate, options)
 	if err != nil {
 		writeError(w, err)
 		return
@@ -163,8 +516,93 @@ func (s *CurrencyServer) handleRatesPost(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, http.StatusCreated, rate)
 }
 
-func (s *CurrencyServer) handleRateByID(w http.ResponseWriter, r *http.Request) {
-	idStr := strings.TrimPrefix(r.URL.Path, "/rates/")
+// handleRateResource serves /rates/{id}, /rates/{id}/history and
+// /rates/{base}/{target}.
+func (s *CurrencyServer) handleRateResource(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/rates/")
+	if base, target, ok := strings.Cut(path, "/"); ok && target != "history" {
+		s.handleRateByPair(w, r, base, target)
+		return
+	}
+	s.handleRateByID(w, r, path)
+}
+
+func (s *CurrencyServer) handleRateByPair(w http.ResponseWriter, r *http.Request, base string, target string) {
+	if base == "" || target == "" || strings.Contains(target, "/") {
+		writeError(w, apperror.Validation("invalid currency pair", "expected /rates/{base}/{target}"))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.handleRateByPairGet(w, r, base, target)
+	case http.MethodPut:
+		s.handleRateByPairPut(w, r, base, target)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// @Summary Get exchange rate by currency pair
+// @Tags rates
+// @Accept json
+// @Produce json
+// @Param base path string true "Base currency code"
+// @Param target path string true "Target currency code"
+// @Success 200 {object} dto.ExchangeRateDto
+// @Failure 400 {object} dto.ErrorDto
+// @Failure 404 {object} dto.ErrorDto
+// @Failure 500 {object} dto.ErrorDto
+// @Router /rates/{base}/{target} [get]
+func (s *CurrencyServer) handleRateByPairGet(w http.ResponseWriter, r *http.Request, base string, target string) {
+	rate, err := s.exchangeService.GetRateByPair(base, target)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rate)
+}
+
+// @Summary Create or update exchange rate by currency pair
+// @Tags rates
+// @Accept json
+// @Produce json
+// @Param base path string true "Base currency code"
+// @Param target path string true "Target currency code"
+// @Param request body dto.PutRateRequest true "Rate payload"
+// @Param X-Actor header string false "Who changes the rate; recorded in the rate history"
+// @Param override query bool false "Apply rates beyond the change guardrail"
+// @Param overrideReason query string false "Why the guardrail is overridden; required with override"
+// @Success 200 {object} dto.ExchangeRateDto
+// @Success 201 {object} dto.ExchangeRateDto
+// @Failure 400 {object} dto.ErrorDto
+// @Failure 404 {object} dto.ErrorDto
+// @Failure 500 {object} dto.ErrorDto
+// @Router /rates/{base}/{target} [put]
+func (s *CurrencyServer) handleRateByPairPut(w http.ResponseWriter, r *http.Request, base string, target string) {
+	var req dto.PutRateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, apperror.Validation("invalid request", err.Error()))
+		return
+	}
+	options, err := parseRateWriteOptions(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rate, created, err := s.exchangeService.UpsertRate(base, target, req.Rate, options)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, rate)
+}
+
+func (s *CurrencyServer) handleRateByID(w http.ResponseWriter, r *http.Request, path string) {
+	idStr, isHistory := strings.CutSuffix(path, "/history")
 	if idStr == "" {
 		writeError(w, apperror.Validation("rate id is required", "empty id"))
 		return
@@ -175,11 +613,22 @@ func (s *CurrencyServer) handleRateByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if isHistory {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		s.handleRateHistoryGet(w, r, id)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		s.handleRateByIDGet(w, r, id)
 	case http.MethodPut:
 		s.handleRateByIDPut(w, r, id)
+	case http.MethodDelete:
+		s.handleRateByIDDelete(w, r, id)
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
@@ -204,15 +653,39 @@ func (s *CurrencyServer) handleRateByIDGet(w http.ResponseWriter, r *http.Reques
 	writeJSON(w, http.StatusOK, rate)
 }
 
+// @Summary Get exchange rate history
+// @Tags rates
+// @Accept json
+// @Produce json
+// @Param id path int true "Rate ID"
+// @Success 200 {array} dto.ExchangeRateVersionDto
+// @Failure 400 {object} dto.ErrorDto
+// @Failure 404 {object} dto.ErrorDto
+// @Failure 500 {object} dto.ErrorDto
+// @Router /rates/{id}/history [get]
+func (s *CurrencyServer) handleRateHistoryGet(w http.ResponseWriter, r *http.Request, id int64) {
+	versions, err := s.exchangeService.GetRateHistory(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
 // @Summary Update exchange rate
+// @Description Changing the currencies moves the rate to another pair and closes the history of the old pair with a deletion version.
 // @Tags rates
 // @Accept json
 // @Produce json
 // @Param id path int true "Rate ID"
 // @Param request body dto.UpdateRateRequest true "Rate payload"
+// @Param X-Actor header string false "Who changes the rate; recorded in the rate history"
+// @Param override query bool false "Apply rates beyond the change guardrail"
+// @Param overrideReason query string false "Why the guardrail is overridden; required with override"
 // @Success 200 {object} dto.ExchangeRateDto
 // @Failure 400 {object} dto.ErrorDto
 // @Failure 404 {object} dto.ErrorDto
+// @Failure 409 {object} dto.ErrorDto
 // @Failure 500 {object} dto.ErrorDto
 // @Router /rates/{id} [put]
 func (s *CurrencyServer) handleRateByIDPut(w http.ResponseWriter, r *http.Request, id int64) {
@@ -221,7 +694,12 @@ func (s *CurrencyServer) handleRateByIDPut(w http.ResponseWriter, r *http.Reques
 		writeError(w, apperror.Validation("invalid request", err.Error()))
 		return
 	}
-	rate, err := s.exchangeService.UpdateRate(id, req.BaseCode, req.TargetCode, req.Rate)
+	options, err := parseRateWriteOptions(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rate, err := s.exchangeService.UpdateRate(id, req.BaseCode, req.TargetCode, req.Rate, options)
 	if err != nil {
 		writeError(w, err)
 		return
@@ -229,16 +707,79 @@ func (s *CurrencyServer) handleRateByIDPut(w http.ResponseWriter, r *http.Reques
 	writeJSON(w, http.StatusOK, rate)
 }
 
+// @Summary Delete exchange rate
+// @Tags rates
+// @Accept json
+// @Produce json
+// @Param id path int true "Rate ID"
+// @Param X-Actor header string false "Who deletes the rate; recorded in the rate history"
+// @Success 204
+// @Failure 400 {object} dto.ErrorDto
+// @Failure 404 {object} dto.ErrorDto
+// @Failure 500 {object} dto.ErrorDto
+// @Router /rates/{id} [delete]
+func (s *CurrencyServer) handleRateByIDDelete(w http.ResponseWriter, r *http.Request, id int64) {
+	if err := s.exchangeService.DeleteRate(id, r.Header.Get(actorHeader)); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Import exchange rates
+// @Description Validates every row and writes all new and changed rates in one transaction. Nothing is written when any row is invalid or dryRun is set; the report lists every row either way. Rates beyond the change guardrail are invalid unless override is set.
+// @Tags rates
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param format query string false "Input format; defaults from Content-Type" Enums(csv, json)
+// @Param dryRun query bool false "Only report the diff against the current rate book"
+// @Param request body []dto.RateImportItemRequest true "base,target,rate rows"
+// @Param X-Actor header string false "Who changes the rate; recorded in the rate history"
+// @Param override query bool false "Apply rates beyond the change guardrail"
+// @Param overrideReason query string false "Why the guardrail is overridden; required with override"
+// @Success 200 {object} dto.RateImportDto
+// @Failure 400 {object} dto.ErrorDto
+// @Failure 500 {object} dto.ErrorDto
+// @Router /rates/import [post]
+func (s *CurrencyServer) handleRateImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	dryRun, err := parseBoolParam(r, "dryRun")
+	if err != nil {
+		writeError(w, apperror.Validation("invalid dryRun flag", err.Error()))
+		return
+	}
+	options, err := parseRateWriteOptions(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	body := newImportBody(w, r)
+	result, err := s.exchangeService.ImportRates(importFormat(r), body, dryRun, options)
+	if err != nil {
+		writeError(w, body.wrapError(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
 // @Summary Exchange currency
 // @Tags exchange
 // @Accept json
 // @Produce json
 // @Param base query string true "Base currency code"
 // @Param target query string true "Target currency code"
-// @Param amount query string true "Amount to exchange"
+// @Param amount query string false "Amount of base currency to exchange; mutually exclusive with targetAmount"
+// @Param targetAmount query string false "Amount of target currency to receive; returns the required base amount"
+// @Param date query string false "Convert at the rates in force at this RFC 3339 timestamp, or at the end of this YYYY-MM-DD day (UTC)"
+// @Param rounding query string false "Rounding of converted amounts to the target minor units" Enums(half_up, half_even, down, up)
 // @Success 200 {object} dto.ExchangeDto
 // @Failure 400 {object} dto.ErrorDto
 // @Failure 404 {object} dto.ErrorDto
+// @Failure 422 {object} dto.ErrorDto "A currency is disabled, or the freshness policy rejects stale rates and only stale rates reach the pair"
 // @Failure 500 {object} dto.ErrorDto
 // @Router /exchange [get]
 func (s *CurrencyServer) handleExchange(w http.ResponseWriter, r *http.Request) {
@@ -249,12 +790,158 @@ func (s *CurrencyServer) handleExchange(w http.ResponseWriter, r *http.Request)
 	baseCode := r.URL.Query().Get("base")
 	targetCode := r.URL.Query().Get("target")
 	amountStr := r.URL.Query().Get("amount")
-	amount, err := decimal.NewFromString(amountStr)
+	targetAmountStr := r.URL.Query().Get("targetAmount")
+	if amountStr != "" && targetAmountStr != "" {
+		writeError(w, apperror.Validation("invalid amount", "amount and targetAmount are mutually exclusive"))
+		return
+	}
+	options, err := parseExchangeOptions(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var result dto.ExchangeDto
+	if targetAmountStr != "" {
+		targetAmount, err := decimal.NewFromString(targetAmountStr)
+		if err != nil {
+			writeError(w, apperror.Validation("invalid target amount", err.Error()))
+			return
+		}
+		result, err = s.exchangeService.ExchangeReverse(baseCode, targetCode, targetAmount, options)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+	} else {
+		amount, err := decimal.NewFromString(amountStr)
+		if err != nil {
+			writeError(w, apperror.Validation("invalid amount", err.Error()))
+			return
+		}
+		result, err = s.exchangeService.Exchange(baseCode, targetCode, amount, options)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// @Summary Exchange a batch of amounts
+// @Tags exchange
+// @Accept json
+// @Produce json
+// @Param date query string false "Convert at the rates in force at this RFC 3339 timestamp, or at the end of this YYYY-MM-DD day (UTC)"
+// @Param rounding query string false "Rounding of converted amounts to the target minor units" Enums(half_up, half_even, down, up)
+// @Param request body dto.ExchangeBatchRequest true "Batch payload"
+// @Success 200 {object} dto.ExchangeBatchDto
+// @Failure 400 {object} dto.ErrorDto
+// @Failure 500 {object} dto.ErrorDto
+// @Router /exchange/batch [post]
+func (s *CurrencyServer) handleExchangeBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	options, err := parseExchangeOptions(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var req dto.ExchangeBatchRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, apperror.Validation("invalid request", err.Error()))
+		return
+	}
+	result, err := s.exchangeService.ExchangeBatch(req.Items, options)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// @Summary Exchange an amount into every reachable currency
+// @Tags exchange
+// @Accept json
+// @Produce json
+// @Param base query string true "Base currency code"
+// @Param amount query string true "Amount to exchange"
+// @Param date query string false "Convert at the rates in force at this RFC 3339 timestamp, or at the end of this YYYY-MM-DD day (UTC)"
+// @Param rounding query string false "Rounding of converted amounts to the target minor units" Enums(half_up, half_even, down, up)
+// @Success 200 {object} dto.ExchangeAllDto
+// @Failure 400 {object} dto.ErrorDto
+// @Failure 404 {object} dto.ErrorDto
+// @Failure 422 {object} dto.ErrorDto "The base currency is disabled"
+// @Failure 500 {object} dto.ErrorDto
+// @Router /exchange/all [get]
+func (s *CurrencyServer) handleExchangeAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	baseCode := r.URL.Query().Get("base")
+	amount, err := decimal.NewFromString(r.URL.Query().Get("amount"))
 	if err != nil {
 		writeError(w, apperror.Validation("invalid amount", err.Error()))
 		return
 	}
-	result, err := s.exchangeService.Exchange(baseCode, targetCode, amount)
+	options, err := parseExchangeOptions(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := s.exchangeService.ExchangeAll(baseCode, amount, options)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// @Summary List stale rates
+// @Description Stored rates older than the freshness policy allows, oldest first.
+// @Tags rates
+// @Produce json
+// @Success 200 {array} dto.StaleRateDto
+// @Failure 500 {object} dto.ErrorDto
+// @Router /rates/stale [get]
+func (s *CurrencyServer) handleStaleRates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	result, err := s.exchangeService.GetStaleRates()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// @Summary Cross-rate matrix
+// @Tags rates
+// @Accept json
+// @Produce json
+// @Param codes query string false "Comma separated currency codes; all currencies when omitted"
+// @Param date query string false "Use the rates in force at this RFC 3339 timestamp, or at the end of this YYYY-MM-DD day (UTC)"
+// @Success 200 {object} dto.RateMatrixDto
+// @Failure 400 {object} dto.ErrorDto
+// @Failure 404 {object} dto.ErrorDto
+// @Failure 500 {object} dto.ErrorDto
+// @Router /rates/matrix [get]
+func (s *CurrencyServer) handleRateMatrix(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	at, err := parseAsOf(r.URL.Query().Get("date"))
+	if err != nil {
+		writeError(w, apperror.Validation("invalid date", err.Error()))
+		return
+	}
+	result, err := s.exchangeService.GetRateMatrix(parseCodes(r.URL.Query().Get("codes")), at)
 	if err != nil {
 		writeError(w, err)
 		return
@@ -266,7 +953,7 @@ func parsePageRequest(r *http.Request) (int32, int32, error) {
 	pageNumberStr := r.URL.Query().Get("pageNumber")
 	pageSizeStr := r.URL.Query().Get("pageSize")
 	if pageNumberStr == "" && pageSizeStr == "" {
-		return 1, 20, nil
+		return 1, defaultPageSize, nil
 	}
 	pageNumber, err := strconv.ParseInt(pageNumberStr, 10, 32)
 	if err != nil {
@@ -279,6 +966,213 @@ func parsePageRequest(r *http.Request) (int32, int32, error) {
 	return int32(pageNumber), int32(pageSize), nil
 }
 
+// parseCursorRequest reads the cursor and pageSize of a keyset listing.
+func parseCursorRequest(r *http.Request) (pagination.CursorRequest, error) {
+	request := pagination.CursorRequest{PageSize: defaultPageSize}
+	if value := r.URL.Query().Get("pageSize"); value != "" {
+		pageSize, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return pagination.CursorRequest{}, apperror.Validation("invalid pagination", err.Error())
+		}
+		request.PageSize = int32(pageSize)
+	}
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		cursor, err := pagination.DecodeCursor(token)
+		if err != nil {
+			return pagination.CursorRequest{}, apperror.Validation("invalid cursor", err.Error())
+		}
+		request.Position = &cursor
+	}
+	return request, nil
+}
+
+// setLinkHeader advertises the neighbouring keyset pages as RFC 8288 links
+// that repeat the current query with only the cursor replaced.
+func setLinkHeader(w http.ResponseWriter, r *http.Request, next string, prev string) {
+	var links []string
+	for _, link := range []struct{ rel, cursor string }{{"next", next}, {"prev", prev}} {
+		if link.cursor == "" {
+			continue
+		}
+		query := r.URL.Query()
+		query.Set("cursor", link.cursor)
+		target := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+		links = append(links, "<"+target.String()+`>; rel="`+link.rel+`"`)
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// parseCurrencyFilter reads the listing filters. Disabled currencies are
+// hidden unless disabled=true or disabled=all is given; the ISO 4217 active
+// flag only filters when active is set.
+func parseCurrencyFilter(r *http.Request) (repository.CurrencyFilter, error) {
+	filter := repository.CurrencyFilter{
+		Search: r.URL.Query().Get("search"),
+		Codes:  parseCodes(r.URL.Query().Get("codes")),
+	}
+	active, err := parseStateFilter(r, "active", nil)
+	if err != nil {
+		return repository.CurrencyFilter{}, err
+	}
+	enabled := false
+	disabled, err := parseStateFilter(r, "disabled", &enabled)
+	if err != nil {
+		return repository.CurrencyFilter{}, err
+	}
+	filter.Active, filter.Disabled = active, disabled
+	return filter, nil
+}
+
+// parseStateFilter reads a true, false or all query parameter. all, and an
+// omitted parameter without a fallback, do not filter.
+func parseStateFilter(r *http.Request, name string, fallback *bool) (*bool, error) {
+	switch value := r.URL.Query().Get(name); value {
+	case "all":
+		return nil, nil
+	case "":
+		return fallback, nil
+	default:
+		state, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, apperror.Validation("invalid "+name+" filter", name+" must be true, false or all")
+		}
+		return &state, nil
+	}
+}
+
+// parseCodes splits a comma separated list of currency codes, dropping blanks
+// and duplicates while keeping the original order.
+func parseCodes(value string) []string {
+	var codes []string
+	seen := make(map[string]bool)
+	for _, code := range strings.Split(value, ",") {
+		code = strings.TrimSpace(code)
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+func parseExchangeOptions(r *http.Request) (service.ExchangeOptions, error) {
+	at, err := parseAsOf(r.URL.Query().Get("date"))
+	if err != nil {
+		return service.ExchangeOptions{}, apperror.Validation("invalid date", err.Error())
+	}
+	options := service.ExchangeOptions{At: at}
+	if value := r.URL.Query().Get("rounding"); value != "" {
+		options.Rounding, err = conversion.ParseRoundingMode(value)
+		if err != nil {
+			return service.ExchangeOptions{}, apperror.Validation("invalid rounding mode", err.Error())
+		}
+	}
+	return options, nil
+}
+
+// parseAsOf accepts either an RFC 3339 timestamp or a plain YYYY-MM-DD date.
+// A plain date selects the rates in force at the end of that day in UTC.
+func parseAsOf(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return &at, nil
+	}
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	at := day.Add(24*time.Hour - time.Nanosecond)
+	return &at, nil
+}
+
+// parseSince accepts either an RFC 3339 timestamp or a plain YYYY-MM-DD date,
+// which selects the start of that day in UTC.
+func parseSince(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return &at, nil
+	}
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	return &day, nil
+}
+
+// importFormat picks the format of an import body from the format query
+// parameter, falling back to the Content-Type header and then to JSON.
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		return service.ImportFormatCSV
+	}
+	return service.ImportFormatJSON
+}
+
+// importBody limits an import body to importMaxBodyBytes and remembers
+// whether the limit was hit, which the import itself only sees as a read
+// error.
+type importBody struct {
+	body     io.ReadCloser
+	tooLarge bool
+}
+
+func newImportBody(w http.ResponseWriter, r *http.Request) *importBody {
+	return &importBody{body: http.MaxBytesReader(w, r.Body, importMaxBodyBytes)}
+}
+
+func (b *importBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		b.tooLarge = true
+	}
+	return n, err
+}
+
+// wrapError replaces the error of an import that ran over the body limit.
+func (b *importBody) wrapError(err error) error {
+	if !b.tooLarge {
+		return err
+	}
+	return apperror.Validation(
+		"import is too large",
+		"request body must not exceed "+strconv.Itoa(importMaxBodyBytes)+" bytes",
+	)
+}
+
+// parseRateWriteOptions reads the actor from the X-Actor header and the
+// guardrail override from the override and overrideReason query parameters.
+func parseRateWriteOptions(r *http.Request) (service.RateWriteOptions, error) {
+	override, err := parseBoolParam(r, "override")
+	if err != nil {
+		return service.RateWriteOptions{}, apperror.Validation("invalid override flag", err.Error())
+	}
+	return service.RateWriteOptions{
+		Actor:          r.Header.Get(actorHeader),
+		Override:       override,
+		OverrideReason: r.URL.Query().Get("overrideReason"),
+	}, nil
+}
+
+func parseBoolParam(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
 func decodeJSON(r *http.Request, target any) error {
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
@@ -307,6 +1201,27 @@ func writeError(w http.ResponseWriter, err error) {
 			return
 		}
 		writeJSON(w, http.StatusNotFound, dto.ErrorDto{Message: "not found"})
+	case errors.Is(err, apperror.ErrConflict):
+		var conflictErr *apperror.ConflictError
+		if errors.As(err, &conflictErr) {
+			writeJSON(w, http.StatusConflict, dto.ErrorDto{Message: conflictErr.Message})
+			return
+		}
+		writeJSON(w, http.StatusConflict, dto.ErrorDto{Message: "conflict"})
+	case errors.Is(err, apperror.ErrStale):
+		var staleErr *apperror.StaleError
+		if errors.As(err, &staleErr) {
+			writeJSON(w, http.StatusUnprocessableEntity, dto.ErrorDto{Message: staleErr.Message})
+			return
+		}
+		writeJSON(w, http.StatusUnprocessableEntity, dto.ErrorDto{Message: "stale exchange rate"})
+	case errors.Is(err, apperror.ErrInactive):
+		var inactiveErr *apperror.InactiveError
+		if errors.As(err, &inactiveErr) {
+			writeJSON(w, http.StatusUnprocessableEntity, dto.ErrorDto{Message: inactiveErr.Message})
+			return
+		}
+		writeJSON(w, http.StatusUnprocessableEntity, dto.ErrorDto{Message: "currency is inactive"})
 	case errors.Is(err, apperror.ErrInternal):
 		var internalErr *apperror.InternalError
 		if errors.As(err, &internalErr) {
@@ -0,0 +1,154 @@
+package importer
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	apperror "currency-exchange/internal/error"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// CBRDailyURL publishes the official Bank of Russia rates of the day.
+	// A date_req=DD/MM/YYYY parameter selects another day.
+	CBRDailyURL = "https://www.cbr.ru/scripts/XML_daily.asp"
+
+	cbrBase = "RUB"
+)
+
+// CBRFeed reads the Bank of Russia official rates in the XML_daily.asp
+// format from a URL or a file.
+type CBRFeed struct {
+	Location string
+	Client   *http.Client
+}
+
+// cbrRates maps the feed. Value is the price in roubles of Nominal units of
+// the currency, written with a decimal comma.
+type cbrRates struct {
+	Date    string `xml:"Date,attr"`
+	Valutes []struct {
+		CharCode string `xml:"CharCode"`
+		Nominal  string `xml:"Nominal"`
+		Value    string `xml:"Value"`
+	} `xml:"Valute"`
+}
+
+func (f CBRFeed) Name() string {
+	return "cbr"
+}
+
+func (f CBRFeed) Fetch(ctx context.Context) ([]Quote, error) {
+	log.Printf("cbr_feed.fetch start location=%s", f.Location)
+	body, err := open(ctx, f.Client, f.Location)
+	if err != nil {
+		log.Printf("cbr_feed.fetch error: %v", err)
+		return nil, err
+	}
+	defer body.Close()
+
+	quotes, err := ParseCBR(body)
+	if err != nil {
+		log.Printf("cbr_feed.fetch parse_error: %v", err)
+		return nil, err
+	}
+	log.Printf("cbr_feed.fetch ok quotes=%d", len(quotes))
+	return quotes, nil
+}
+
+// ParseCBR reads a Bank of Russia daily rates document into RUB based
+// quotes: one rouble buys Nominal / Value units of each currency.
+func ParseCBR(input io.Reader) ([]Quote, error) {
+	var document cbrRates
+	decoder := xml.NewDecoder(input)
+	decoder.CharsetReader = charsetReader
+	if err := decoder.Decode(&document); err != nil {
+		return nil, apperror.Validation("invalid CBR feed", err.Error())
+	}
+
+	date, err := time.Parse("02.01.2006", document.Date)
+	if err != nil {
+		return nil, apperror.Validation("invalid CBR feed", "invalid date "+document.Date)
+	}
+	quotes := make([]Quote, 0, len(document.Valutes))
+	for _, item := range document.Valutes {
+		code := strings.ToUpper(strings.TrimSpace(item.CharCode))
+		nominal, err := strconv.ParseInt(strings.TrimSpace(item.Nominal), 10, 64)
+		if err != nil || nominal < 1 {
+			return nil, apperror.Validation("invalid CBR feed", "invalid nominal "+item.Nominal+" for "+code)
+		}
+		value, err := parseDecimalComma(item.Value)
+		if err != nil || !value.IsPositive() {
+			return nil, apperror.Validation("invalid CBR feed", "invalid value "+item.Value+" for "+code)
+		}
+		quotes = append(quotes, Quote{
+			Date:   date,
+			Base:   cbrBase,
+			Target: code,
+			Rate:   decimal.NewFromInt(nominal).Div(value),
+		})
+	}
+	return quotes, nil
+}
+
+// parseDecimalComma parses a number written with a decimal comma.
+func parseDecimalComma(value string) (decimal.Decimal, error) {
+	return decimal.NewFromString(strings.Replace(strings.TrimSpace(value), ",", ".", 1))
+}
+
+// charsetReader lets encoding/xml read documents declared as windows-1251,
+// which the standard library does not decode.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "windows-1251", "cp1251":
+		content, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(decodeWindows1251(content)), nil
+	default:
+		return nil, apperror.Validation("unsupported feed encoding", charset)
+	}
+}
+
+func decodeWindows1251(content []byte) string {
+	var builder strings.Builder
+	builder.Grow(len(content) * 2)
+	for _, b := range content {
+		if b < utf8.RuneSelf {
+			builder.WriteByte(b)
+			continue
+		}
+		builder.WriteRune(windows1251[b-utf8.RuneSelf])
+	}
+	return builder.String()
+}
+
+// windows1251 maps the bytes 0x80-0xFF of code page 1251 to Unicode; 0x98 is
+// unassigned.
+var windows1251 = [128]rune{
+	0x0402, 0x0403, 0x201A, 0x0453, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x20AC, 0x2030, 0x0409, 0x2039, 0x040A, 0x040C, 0x040B, 0x040F,
+	0x0452, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0xFFFD, 0x2122, 0x0459, 0x203A, 0x045A, 0x045C, 0x045B, 0x045F,
+	0x00A0, 0x040E, 0x045E, 0x0408, 0x00A4, 0x0490, 0x00A6, 0x00A7,
+	0x0401, 0x00A9, 0x0404, 0x00AB, 0x00AC, 0x00AD, 0x00AE, 0x0407,
+	0x00B0, 0x00B1, 0x0406, 0x0456, 0x0491, 0x00B5, 0x00B6, 0x00B7,
+	0x0451, 0x2116, 0x0454, 0x00BB, 0x0458, 0x0405, 0x0455, 0x0457,
+	0x0410, 0x0411, 0x0412, 0x0413, 0x0414, 0x0415, 0x0416, 0x0417,
+	0x0418, 0x0419, 0x041A, 0x041B, 0x041C, 0x041D, 0x041E, 0x041F,
+	0x0420, 0x0421, 0x0422, 0x0423, 0x0424, 0x0425, 0x0426, 0x0427,
+	0x0428, 0x0429, 0x042A, 0x042B, 0x042C, 0x042D, 0x042E, 0x042F,
+	0x0430, 0x0431, 0x0432, 0x0433, 0x0434, 0x0435, 0x0436, 0x0437,
+	0x0438, 0x0439, 0x043A, 0x043B, 0x043C, 0x043D, 0x043E, 0x043F,
+	0x0440, 0x0441, 0x0442, 0x0443, 0x0444, 0x0445, 0x0446, 0x0447,
+	0x0448, 0x0449, 0x044A, 0x044B, 0x044C, 0x044D, 0x044E, 0x044F,
+}
@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"currency-exchange/internal/entity"
+)
+
+// cbrDaily mirrors XML_daily.asp: windows-1251 encoded, decimal commas and a
+// Nominal above one for cheap currencies.
+const cbrDaily = `<?xml version="1.0" encoding="windows-1251"?>
+<ValCurs Date="17.10.2026" name="Foreign Currency Market">
+<Valute ID="R01235"><NumCode>840</NumCode><CharCode>USD</CharCode><Nominal>1</Nominal><Name>` +
+	"\xc4\xee\xeb\xeb\xe0\xf0 \xd1\xd8\xc0" + `</Name><Value>80,5000</Value></Valute>
+<Valute ID="R01820"><NumCode>392</NumCode><CharCode>jpy</CharCode><Nominal>100</Nominal><Name>Yen</Name><Value>53,2000</Value></Valute>
+</ValCurs>`
+
+func TestCBRFeedFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml; charset=windows-1251")
+		w.Write([]byte(cbrDaily))
+	}))
+	defer server.Close()
+
+	quotes, err := CBRFeed{Location: server.URL, Client: server.Client()}.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	date := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	want := []struct {
+		target string
+		rate   string
+	}{
+		{target: "USD", rate: "0.012422"},
+		{target: "JPY", rate: "1.879699"},
+	}
+	if len(quotes) != len(want) {
+		t.Fatalf("Fetch() returned %d quotes, want %d", len(quotes), len(want))
+	}
+	for i, quote := range quotes {
+		if quote.Base != "RUB" || quote.Target != want[i].target || !quote.Date.Equal(date) {
+			t.Errorf("quote %d = %s/%s on %s, want RUB/%s on %s", i, quote.Base, quote.Target, quote.Date, want[i].target, date)
+		}
+		if rate := quote.Rate.Round(entity.ExchangeRateMaxScale).String(); rate != want[i].rate {
+			t.Errorf("quote %d rate = %s, want %s", i, rate, want[i].rate)
+		}
+	}
+}
+
+func TestParseCBRRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		valute string
+	}{
+		{name: "zero nominal", valute: `<CharCode>USD</CharCode><Nominal>0</Nominal><Value>80,5</Value>`},
+		{name: "malformed value", valute: `<CharCode>USD</CharCode><Nominal>1</Nominal><Value>80;5</Value>`},
+		{name: "zero value", valute: `<CharCode>USD</CharCode><Nominal>1</Nominal><Value>0,0000</Value>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document := `<ValCurs Date="17.10.2026"><Valute>` + tt.valute + `</Valute></ValCurs>`
+			if _, err := ParseCBR(strings.NewReader(document)); err == nil {
+				t.Fatalf("ParseCBR() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestDecodeWindows1251(t *testing.T) {
+	if got := decodeWindows1251([]byte("\xc4\xee\xeb\xeb\xe0\xf0 \xd1\xd8\xc0")); got != "Доллар США" {
+		t.Errorf("decodeWindows1251() = %q, want %q", got, "Доллар США")
+	}
+}
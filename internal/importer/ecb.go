@@ -0,0 +1,97 @@
+package importer
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	apperror "currency-exchange/internal/error"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// ECBDailyURL publishes the euro reference rates of the latest working day.
+	ECBDailyURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+	// ECBHistoryURL publishes every euro reference rate since 1999.
+	ECBHistoryURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist.xml"
+
+	ecbBase = "EUR"
+)
+
+// ECBFeed reads the European Central Bank euro reference rates in the
+// eurofxref-daily.xml or eurofxref-hist.xml format from a URL or a file.
+type ECBFeed struct {
+	Location string
+	Client   *http.Client
+}
+
+// ecbEnvelope maps the nested Cube elements of the feed: one per
+// publication day, each holding one per currency.
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+func (f ECBFeed) Name() string {
+	return "ecb"
+}
+
+func (f ECBFeed) Fetch(ctx context.Context) ([]Quote, error) {
+	log.Printf("ecb_feed.fetch start location=%s", f.Location)
+	body, err := open(ctx, f.Client, f.Location)
+	if err != nil {
+		log.Printf("ecb_feed.fetch error: %v", err)
+		return nil, err
+	}
+	defer body.Close()
+
+	quotes, err := ParseECB(body)
+	if err != nil {
+		log.Printf("ecb_feed.fetch parse_error: %v", err)
+		return nil, err
+	}
+	log.Printf("ecb_feed.fetch ok quotes=%d", len(quotes))
+	return quotes, nil
+}
+
+// ParseECB reads an ECB reference rate document into EUR based quotes.
+func ParseECB(input io.Reader) ([]Quote, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(input).Decode(&envelope); err != nil {
+		return nil, apperror.Validation("invalid ECB feed", err.Error())
+	}
+
+	var quotes []Quote
+	for _, day := range envelope.Days {
+		date, err := time.Parse(time.DateOnly, day.Time)
+		if err != nil {
+			return nil, apperror.Validation("invalid ECB feed", "invalid date "+day.Time)
+		}
+		for _, item := range day.Rates {
+			rate, err := decimal.NewFromString(item.Rate)
+			if err != nil {
+				return nil, apperror.Validation(
+					"invalid ECB feed",
+					"invalid rate "+item.Rate+" for "+item.Currency+" on "+day.Time,
+				)
+			}
+			quotes = append(quotes, Quote{
+				Date:   date,
+				Base:   ecbBase,
+				Target: strings.ToUpper(strings.TrimSpace(item.Currency)),
+				Rate:   rate,
+			})
+		}
+	}
+	return quotes, nil
+}
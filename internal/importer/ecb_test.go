@@ -0,0 +1,64 @@
+package importer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// ecbHistory mirrors eurofxref-hist.xml: an outer Cube holding one Cube per
+// day, each holding one Cube per currency.
+const ecbHistory = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2026-10-16">
+			<Cube currency="USD" rate="1.0921"/>
+			<Cube currency="JPY" rate="162.45"/>
+		</Cube>
+		<Cube time="2026-10-15">
+			<Cube currency="USD" rate="1.0894"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func TestECBFeedFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(ecbHistory))
+	}))
+	defer server.Close()
+
+	quotes, err := ECBFeed{Location: server.URL, Client: server.Client()}.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	want := []struct {
+		date   string
+		target string
+		rate   string
+	}{
+		{date: "2026-10-16", target: "USD", rate: "1.0921"},
+		{date: "2026-10-16", target: "JPY", rate: "162.45"},
+		{date: "2026-10-15", target: "USD", rate: "1.0894"},
+	}
+	if len(quotes) != len(want) {
+		t.Fatalf("Fetch() returned %d quotes, want %d", len(quotes), len(want))
+	}
+	for i, quote := range quotes {
+		got := quote.Date.Format("2006-01-02") + " " + quote.Base + "/" + quote.Target + " " + quote.Rate.String()
+		if expected := want[i].date + " EUR/" + want[i].target + " " + want[i].rate; got != expected {
+			t.Errorf("quote %d = %s, want %s", i, got, expected)
+		}
+	}
+}
+
+func TestParseECBRejectsInvalidRate(t *testing.T) {
+	document := `<Envelope><Cube><Cube time="2026-10-16"><Cube currency="USD" rate="n/a"/></Cube></Cube></Envelope>`
+	if _, err := ParseECB(strings.NewReader(document)); err == nil {
+		t.Fatalf("ParseECB() error = nil, want an error")
+	}
+}
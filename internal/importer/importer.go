@@ -0,0 +1,290 @@
+// Package importer loads official exchange rates published by central banks
+// into the rate book.
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"currency-exchange/internal/entity"
+	apperror "currency-exchange/internal/error"
+	"currency-exchange/internal/repository"
+	"currency-exchange/internal/service"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultTimeout bounds a feed download when no client is configured.
+const DefaultTimeout = 30 * time.Second
+
+// Quote is one published rate: one unit of Base buys Rate units of Target
+// on the publication date Date.
+type Quote struct {
+	Date   time.Time
+	Base   string
+	Target string
+	Rate   decimal.Decimal
+}
+
+// Feed is a source of published quotes.
+type Feed interface {
+	Name() string
+	Fetch(ctx context.Context) ([]Quote, error)
+}
+
+type Options struct {
+	// History writes every publication day of the feed, oldest first, with
+	// the publication date as the effective date, so the days land in the
+	// rate history. Days not newer than the stored rate are left out.
+	// Otherwise only the latest day is written, effective now.
+	History bool
+	// OverrideReason, when set, writes rates beyond the rate change
+	// guardrail and records the reason in their history.
+	OverrideReason string
+}
+
+// Guard applies the rate change guardrail to the rates of an import.
+// ExchangeService implements it.
+type Guard interface {
+	GuardRates(
+		current []entity.ExchangeRate,
+		rates []entity.ExchangeRate,
+		options service.RateWriteOptions,
+	) ([]entity.ExchangeRate, []service.RateRejection, error)
+}
+
+// SkippedCurrency reports a currency whose quotes were not written.
+type SkippedCurrency struct {
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+	Quotes int    `json:"quotes"`
+}
+
+type Report struct {
+	Feed string `json:"feed"`
+	// Published is the latest publication date in the feed.
+	Published string `json:"published"`
+	Days      int    `json:"days"`
+	Written   int    `json:"written"`
+	// Outdated counts history quotes not newer than the stored rate.
+	Outdated int               `json:"outdated"`
+	Skipped  []SkippedCurrency `json:"skipped"`
+}
+
+// Importer writes the quotes of a feed through ExchangeRepository, after
+// checking them with the rate change guardrail.
+type Importer struct {
+	ctx                context.Context
+	exchangeRepository repository.ExchangeRepository
+	currencyRepository repository.CurrencyRepository
+	guard              Guard
+}
+
+func New(
+	ctx context.Context,
+	exchangeRepository repository.ExchangeRepository,
+	currencyRepository repository.CurrencyRepository,
+	guard Guard,
+) *Importer {
+	return &Importer{
+		ctx:                ctx,
+		exchangeRepository: exchangeRepository,
+		currencyRepository: currencyRepository,
+		guard:              guard,
+	}
+}
+
+// Import fetches the feed and writes its quotes in one transaction.
+// Quotes of currencies missing from the currencies table, or disabled there,
+// and quotes beyond the rate change guardrail are skipped and listed in the
+// report.
+//
+// Rates are stored rounded to entity.ExchangeRateMaxScale decimal places.
+// The CBR and NBRB feeds publish the rouble price of each currency, which
+// is inverted into a rouble based rate, so the rates of currencies dearer
+// than the rouble keep few significant digits: RUB/KWD, about 0.0038, is
+// stored with four, a relative error of up to about 1e-4. Conversions the
+// other way, such as KWD/RUB, are best taken from a feed publishing them
+// directly.
+func (i *Importer) Import(feed Feed, options Options) (Report, error) {
+	log.Printf("importer.import start feed=%s history=%t", feed.Name(), options.History)
+	quotes, err := feed.Fetch(i.ctx)
+	if err != nil {
+		log.Printf("importer.import fetch_error feed=%s: %v", feed.Name(), err)
+		return Report{}, err
+	}
+	report := Report{Feed: feed.Name(), Skipped: []SkippedCurrency{}}
+	if len(quotes) == 0 {
+		log.Printf("importer.import ok feed=%s empty", feed.Name())
+		return report, nil
+	}
+
+	sort.SliceStable(quotes, func(a, b int) bool { return quotes[a].Date.Before(quotes[b].Date) })
+	latest := quotes[len(quotes)-1].Date
+	report.Published = latest.Format(time.DateOnly)
+	if !options.History {
+		quotes = quotesOn(quotes, latest)
+	}
+	report.Days = countDays(quotes)
+
+	currencies, err := i.currencyRepository.GetAll(i.ctx)
+	if err != nil {
+		log.Printf("importer.import currencies_error: %v", err)
+		return Report{}, apperror.Internal("get currencies", err.Error())
+	}
+	byCode := make(map[string]entity.Currency, len(currencies))
+	for _, currency := range currencies {
+		byCode[currency.Code] = currency
+	}
+	current, err := i.exchangeRepository.GetAll(i.ctx)
+	if err != nil {
+		log.Printf("importer.import rates_error: %v", err)
+		return Report{}, apperror.Internal("get exchange rates", err.Error())
+	}
+	effective := make(map[[2]int64]time.Time, len(current))
+	for _, rate := range current {
+		effective[[2]int64{rate.BaseCurrency.ID, rate.TargetCurrency.ID}] = rate.EffectiveFrom
+	}
+
+	now := time.Now().UTC()
+	skipped := make(map[string]*SkippedCurrency)
+	skip := func(code string, reason string) {
+		if entry, ok := skipped[code]; ok {
+			entry.Quotes++
+			return
+		}
+		skipped[code] = &SkippedCurrency{Code: code, Reason: reason, Quotes: 1}
+	}
+	var rates []entity.ExchangeRate
+	for _, quote := range quotes {
+		base, ok := byCode[quote.Base]
+		if !ok || base.Disabled {
+			log.Printf("importer.import validation_error base=%s", quote.Base)
+			return Report{}, apperror.Validation(
+				"base currency is not available",
+				"currency "+quote.Base+" is missing or disabled",
+			)
+		}
+		target, ok := byCode[quote.Target]
+		switch {
+		case !ok:
+			skip(quote.Target, "missing from currencies table")
+			continue
+		case target.Disabled:
+			skip(quote.Target, "disabled currency")
+			continue
+		case target.ID == base.ID:
+			continue
+		}
+		rate := quote.Rate.Round(entity.ExchangeRateMaxScale)
+		if !rate.IsPositive() {
+			skip(quote.Target, "rate is not positive at "+fmt.Sprint(entity.ExchangeRateMaxScale)+" decimal places")
+			continue
+		}
+		at := now
+		if options.History {
+			at = quote.Date
+			if stored, ok := effective[[2]int64{base.ID, target.ID}]; ok && !at.After(stored) {
+				report.Outdated++
+				continue
+			}
+		}
+		rates = append(rates, entity.ExchangeRate{
+			BaseCurrency:   base,
+			TargetCurrency: target,
+			Rate:           rate,
+			EffectiveFrom:  at,
+			Sources:        []string{feed.Name()},
+			UpdatedAt:      now,
+		})
+	}
+	rates, rejected, err := i.guard.GuardRates(current, rates, service.RateWriteOptions{
+		Actor:          feed.Name(),
+		Override:       options.OverrideReason != "",
+		OverrideReason: options.OverrideReason,
+	})
+	if err != nil {
+		log.Printf("importer.import guard_error feed=%s: %v", feed.Name(), err)
+		return Report{}, err
+	}
+	for _, rejection := range rejected {
+		skip(rejection.Rate.TargetCurrency.Code, apperror.MessageOf(rejection.Err))
+	}
+	for _, code := range sortedKeys(skipped) {
+		report.Skipped = append(report.Skipped, *skipped[code])
+	}
+
+	if len(rates) > 0 {
+		if err := i.exchangeRepository.UpsertAll(i.ctx, rates); err != nil {
+			log.Printf("importer.import error feed=%s: %v", feed.Name(), err)
+			return Report{}, err
+		}
+	}
+	report.Written = len(rates)
+	log.Printf("importer.import ok feed=%s published=%s written=%d outdated=%d skipped=%d",
+		feed.Name(), report.Published, report.Written, report.Outdated, len(report.Skipped))
+	return report, nil
+}
+
+func quotesOn(quotes []Quote, day time.Time) []Quote {
+	var result []Quote
+	for _, quote := range quotes {
+		if quote.Date.Equal(day) {
+			result = append(result, quote)
+		}
+	}
+	return result
+}
+
+func countDays(quotes []Quote) int {
+	days := make(map[time.Time]bool)
+	for _, quote := range quotes {
+		days[quote.Date] = true
+	}
+	return len(days)
+}
+
+func sortedKeys(skipped map[string]*SkippedCurrency) []string {
+	keys := make([]string, 0, len(skipped))
+	for key := range skipped {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// open returns the content at location, which is either an http(s) URL or
+// a path on the local file system.
+func open(ctx context.Context, client *http.Client, location string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		file, err := os.Open(location)
+		if err != nil {
+			return nil, apperror.Internal("open rate feed", err.Error())
+		}
+		return file, nil
+	}
+
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, apperror.Validation("invalid rate feed url", err.Error())
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, apperror.Internal("fetch rate feed", err.Error())
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, apperror.Internal("fetch rate feed", location+" returned "+response.Status)
+	}
+	return response.Body, nil
+}
@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"currency-exchange/internal/entity"
+	apperror "currency-exchange/internal/error"
+	"currency-exchange/internal/repository"
+	"currency-exchange/internal/service"
+
+	"github.com/shopspring/decimal"
+)
+
+type stubFeed struct {
+	quotes []Quote
+}
+
+func (f stubFeed) Name() string {
+	return "stub"
+}
+
+func (f stubFeed) Fetch(ctx context.Context) ([]Quote, error) {
+	return f.quotes, nil
+}
+
+// The embedded interfaces leave every method Import does not call nil.
+type stubCurrencyRepository struct {
+	repository.CurrencyRepository
+	currencies []entity.Currency
+}
+
+func (r *stubCurrencyRepository) GetAll(ctx context.Context) ([]entity.Currency, error) {
+	return r.currencies, nil
+}
+
+type stubExchangeRepository struct {
+	repository.ExchangeRepository
+	written []entity.ExchangeRate
+}
+
+func (r *stubExchangeRepository) GetAll(ctx context.Context) ([]entity.ExchangeRate, error) {
+	return nil, nil
+}
+
+func (r *stubExchangeRepository) UpsertAll(ctx context.Context, rates []entity.ExchangeRate) error {
+	r.written = append(r.written, rates...)
+	return nil
+}
+
+// stubGuard holds back every rate into the listed targets.
+type stubGuard struct {
+	rejected map[string]bool
+}
+
+func (g stubGuard) GuardRates(
+	current []entity.ExchangeRate,
+	rates []entity.ExchangeRate,
+	options service.RateWriteOptions,
+) ([]entity.ExchangeRate, []service.RateRejection, error) {
+	var (
+		accepted   []entity.ExchangeRate
+		rejections []service.RateRejection
+	)
+	for _, rate := range rates {
+		if g.rejected[rate.TargetCurrency.Code] {
+			rejections = append(rejections, service.RateRejection{
+				Rate: rate,
+				Err:  apperror.Validation("rate change exceeds the guardrail", rate.TargetCurrency.Code),
+			})
+			continue
+		}
+		accepted = append(accepted, rate)
+	}
+	return accepted, rejections, nil
+}
+
+func TestImportReportsSkippedCurrencies(t *testing.T) {
+	currencies := &stubCurrencyRepository{currencies: []entity.Currency{
+		{ID: 1, Code: "EUR"},
+		{ID: 2, Code: "USD"},
+		{ID: 3, Code: "CHF", Disabled: true},
+		{ID: 4, Code: "JPY"},
+		{ID: 5, Code: "IDR"},
+	}}
+	rates := &stubExchangeRepository{}
+	rateImporter := New(context.Background(), rates, currencies, stubGuard{rejected: map[string]bool{"JPY": true}})
+
+	first := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	second := first.AddDate(0, 0, 1)
+	quote := func(date time.Time, target string, rate string) Quote {
+		return Quote{Date: date, Base: "EUR", Target: target, Rate: decimal.RequireFromString(rate)}
+	}
+	feed := stubFeed{quotes: []Quote{
+		quote(second, "USD", "1.0921"),
+		quote(second, "GBP", "0.8612"),
+		quote(second, "CHF", "0.9402"),
+		quote(second, "JPY", "162.45"),
+		quote(second, "IDR", "0.0000001"),
+		quote(first, "USD", "1.0894"),
+		quote(first, "GBP", "0.8598"),
+	}}
+
+	report, err := rateImporter.Import(feed, Options{History: true})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	if report.Published != "2026-10-16" || report.Days != 2 || report.Written != 2 {
+		t.Errorf("report = published %s, days %d, written %d; want 2026-10-16, 2, 2",
+			report.Published, report.Days, report.Written)
+	}
+	wantSkipped := []SkippedCurrency{
+		{Code: "CHF", Reason: "disabled currency", Quotes: 1},
+		{Code: "GBP", Reason: "missing from currencies table", Quotes: 2},
+		{Code: "IDR", Reason: "rate is not positive at 6 decimal places", Quotes: 1},
+		{Code: "JPY", Reason: "rate change exceeds the guardrail", Quotes: 1},
+	}
+	if !reflect.DeepEqual(report.Skipped, wantSkipped) {
+		t.Errorf("report.Skipped = %+v, want %+v", report.Skipped, wantSkipped)
+	}
+
+	if len(rates.written) != 2 {
+		t.Fatalf("wrote %d rates, want 2", len(rates.written))
+	}
+	for i, date := range []time.Time{first, second} {
+		written := rates.written[i]
+		if written.TargetCurrency.Code != "USD" || !written.EffectiveFrom.Equal(date) {
+			t.Errorf("written rate %d = EUR/%s effective %s, want EUR/USD effective %s",
+				i, written.TargetCurrency.Code, written.EffectiveFrom, date)
+		}
+		if !reflect.DeepEqual(written.Sources, []string{"stub"}) {
+			t.Errorf("written rate %d sources = %v, want [stub]", i, written.Sources)
+		}
+	}
+}
+
+func TestImportWritesOnlyTheLatestDayByDefault(t *testing.T) {
+	currencies := &stubCurrencyRepository{currencies: []entity.Currency{{ID: 1, Code: "EUR"}, {ID: 2, Code: "USD"}}}
+	rates := &stubExchangeRepository{}
+	rateImporter := New(context.Background(), rates, currencies, stubGuard{})
+
+	latest := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	feed := stubFeed{quotes: []Quote{
+		{Date: latest.AddDate(0, 0, -1), Base: "EUR", Target: "USD", Rate: decimal.RequireFromString("1.0894")},
+		{Date: latest, Base: "EUR", Target: "USD", Rate: decimal.RequireFromString("1.0921")},
+	}}
+
+	report, err := rateImporter.Import(feed, Options{})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if report.Days != 1 || report.Written != 1 || len(report.Skipped) != 0 {
+		t.Errorf("report = days %d, written %d, skipped %v; want 1, 1, none", report.Days, report.Written, report.Skipped)
+	}
+	if len(rates.written) != 1 || !rates.written[0].Rate.Equal(decimal.RequireFromString("1.0921")) {
+		t.Errorf("written = %+v, want the EUR/USD rate of %s", rates.written, latest.Format(time.DateOnly))
+	}
+}
@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	apperror "currency-exchange/internal/error"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// NBRBBaseURL is the root of the National Bank of the Republic of
+	// Belarus exchange rate API.
+	NBRBBaseURL = "https://api.nbrb.by/exrates"
+
+	// nbrbDailyRates is appended to the base URL to list the daily rates.
+	nbrbDailyRates = "/rates?periodicity=0"
+	nbrbBase       = "BYN"
+)
+
+// NBRBFeed reads the National Bank of the Republic of Belarus official
+// rates. Location is either the base URL of the API or a file holding the
+// JSON of its rates listing.
+type NBRBFeed struct {
+	Location string
+	Client   *http.Client
+}
+
+// nbrbRate is one entry of the rates listing. Cur_OfficialRate is the price
+// in roubles of Cur_Scale units of the currency.
+type nbrbRate struct {
+	Date         string          `json:"Date"`
+	Abbreviation string          `json:"Cur_Abbreviation"`
+	Scale        int64           `json:"Cur_Scale"`
+	OfficialRate decimal.Decimal `json:"Cur_OfficialRate"`
+}
+
+func (f NBRBFeed) Name() string {
+	return "nbrb"
+}
+
+func (f NBRBFeed) Fetch(ctx context.Context) ([]Quote, error) {
+	location := f.Location
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		location = strings.TrimSuffix(location, "/") + nbrbDailyRates
+	}
+	log.Printf("nbrb_feed.fetch start location=%s", location)
+	body, err := open(ctx, f.Client, location)
+	if err != nil {
+		log.Printf("nbrb_feed.fetch error: %v", err)
+		return nil, err
+	}
+	defer body.Close()
+
+	quotes, err := ParseNBRB(body)
+	if err != nil {
+		log.Printf("nbrb_feed.fetch parse_error: %v", err)
+		return nil, err
+	}
+	log.Printf("nbrb_feed.fetch ok quotes=%d", len(quotes))
+	return quotes, nil
+}
+
+// ParseNBRB reads an NBRB rates listing into BYN based quotes: one rouble
+// buys Cur_Scale / Cur_OfficialRate units of each currency.
+func ParseNBRB(input io.Reader) ([]Quote, error) {
+	var items []nbrbRate
+	if err := json.NewDecoder(input).Decode(&items); err != nil {
+		return nil, apperror.Validation("invalid NBRB feed", err.Error())
+	}
+
+	quotes := make([]Quote, 0, len(items))
+	for _, item := range items {
+		code := strings.ToUpper(strings.TrimSpace(item.Abbreviation))
+		date, err := time.Parse("2006-01-02T15:04:05", item.Date)
+		if err != nil {
+			return nil, apperror.Validation("invalid NBRB feed", "invalid date "+item.Date+" for "+code)
+		}
+		if item.Scale < 1 {
+			return nil, apperror.Validation("invalid NBRB feed", "invalid scale for "+code)
+		}
+		if !item.OfficialRate.IsPositive() {
+			return nil, apperror.Validation("invalid NBRB feed", "invalid rate for "+code)
+		}
+		quotes = append(quotes, Quote{
+			Date:   date,
+			Base:   nbrbBase,
+			Target: code,
+			Rate:   decimal.NewFromInt(item.Scale).Div(item.OfficialRate),
+		})
+	}
+	return quotes, nil
+}
@@ -0,0 +1,66 @@
+package importer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"currency-exchange/internal/entity"
+)
+
+// nbrbRates mirrors /exrates/rates?periodicity=0, where Cur_OfficialRate is
+// the price of Cur_Scale units.
+const nbrbRates = `[
+	{"Cur_ID":431,"Date":"2026-10-17T00:00:00","Cur_Abbreviation":"USD","Cur_Scale":1,"Cur_Name":"Доллар США","Cur_OfficialRate":3.2705},
+	{"Cur_ID":456,"Date":"2026-10-17T00:00:00","Cur_Abbreviation":"RUB","Cur_Scale":100,"Cur_Name":"Российских рублей","Cur_OfficialRate":3.9768}
+]`
+
+func TestNBRBFeedFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/exrates/rates" || r.URL.Query().Get("periodicity") != "0" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(nbrbRates))
+	}))
+	defer server.Close()
+
+	quotes, err := NBRBFeed{Location: server.URL + "/exrates/", Client: server.Client()}.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	want := map[string]string{"USD": "0.305764", "RUB": "25.145846"}
+	if len(quotes) != len(want) {
+		t.Fatalf("Fetch() returned %d quotes, want %d", len(quotes), len(want))
+	}
+	for _, quote := range quotes {
+		if quote.Base != "BYN" || quote.Date.Format("2006-01-02") != "2026-10-17" {
+			t.Errorf("quote %s/%s on %s, want BYN base on 2026-10-17", quote.Base, quote.Target, quote.Date)
+		}
+		if rate := quote.Rate.Round(entity.ExchangeRateMaxScale).String(); rate != want[quote.Target] {
+			t.Errorf("%s rate = %s, want %s", quote.Target, rate, want[quote.Target])
+		}
+	}
+}
+
+func TestNBRBFeedFetchReportsHTTPErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if _, err := (NBRBFeed{Location: server.URL, Client: server.Client()}).Fetch(context.Background()); err == nil {
+		t.Fatalf("Fetch() error = nil, want an error")
+	}
+}
+
+func TestParseNBRBRejectsInvalidScale(t *testing.T) {
+	document := `[{"Date":"2026-10-17T00:00:00","Cur_Abbreviation":"USD","Cur_Scale":0,"Cur_OfficialRate":3.2705}]`
+	if _, err := ParseNBRB(strings.NewReader(document)); err == nil {
+		t.Fatalf("ParseNBRB() error = nil, want an error")
+	}
+}
@@ -0,0 +1,90 @@
+// Package iso4217 ships the ISO 4217 currency list inside the binary so
+// currency codes can be validated and the currencies table seeded without
+// network access.
+package iso4217
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed catalog.csv
+var catalogCSV string
+
+// Entry is one currency of the catalog. Sign is a display symbol and not
+// part of the standard.
+type Entry struct {
+	Code        string
+	NumericCode string
+	MinorUnits  int32
+	Name        string
+	Sign        string
+	Active      bool
+	Countries   []string
+}
+
+var entries = mustParse(catalogCSV)
+
+// Lookup finds a currency by its alphabetic code. The code is matched case
+// insensitively.
+func Lookup(code string) (Entry, bool) {
+	entry, ok := entries[strings.ToUpper(code)]
+	return entry, ok
+}
+
+// All returns every catalog entry ordered by code.
+func All() []Entry {
+	result := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Code < result[j].Code })
+	return result
+}
+
+func mustParse(data string) map[string]Entry {
+	parsed, err := parse(data)
+	if err != nil {
+		panic("iso4217: invalid embedded catalog: " + err.Error())
+	}
+	return parsed
+}
+
+func parse(data string) (map[string]Entry, error) {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("empty catalog")
+	}
+
+	parsed := make(map[string]Entry, len(records)-1)
+	for i, record := range records[1:] {
+		if len(record) != 7 {
+			return nil, fmt.Errorf("line %d: expected 7 fields, got %d", i+2, len(record))
+		}
+		minorUnits, err := strconv.ParseInt(record[2], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: minor units: %w", i+2, err)
+		}
+		entry := Entry{
+			Code:        record[0],
+			NumericCode: record[1],
+			MinorUnits:  int32(minorUnits),
+			Name:        record[3],
+			Sign:        record[4],
+			Active:      record[5] == "1",
+			Countries:   strings.Fields(record[6]),
+		}
+		if _, exists := parsed[entry.Code]; exists {
+			return nil, fmt.Errorf("line %d: duplicate code %s", i+2, entry.Code)
+		}
+		parsed[entry.Code] = entry
+	}
+	return parsed, nil
+}
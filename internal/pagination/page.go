@@ -1,8 +1,37 @@
 package pagination
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+type SortDirection string
+
+const (
+	SortAsc  SortDirection = "asc"
+	SortDesc SortDirection = "desc"
+)
+
+// ParseSortDirection accepts "asc" or "desc"; an empty value means ascending.
+func ParseSortDirection(value string) (SortDirection, error) {
+	switch SortDirection(value) {
+	case "", SortAsc:
+		return SortAsc, nil
+	case SortDesc:
+		return SortDesc, nil
+	default:
+		return "", fmt.Errorf("unknown sort direction %q, expected %s or %s", value, SortAsc, SortDesc)
+	}
+}
+
 type PageRequest struct {
 	PageNumber int32
 	PageSize   int32
+	// SortBy names the field to order by; empty means the listing default.
+	SortBy    string
+	Direction SortDirection
 }
 
 type Page[T any] struct {
@@ -11,3 +40,55 @@ type Page[T any] struct {
 	PageSize   int32 `json:"pageSize"`
 	Total      int   `json:"total"`
 }
+
+// Cursor is a keyset position in a sorted listing: the sort key and id of the
+// row the page starts after, or ends before when Backward is set. Clients
+// only see it encoded; see EncodeCursor.
+type Cursor struct {
+	Key       string        `json:"k,omitempty"`
+	ID        int64         `json:"i"`
+	Backward  bool          `json:"b,omitempty"`
+	SortBy    string        `json:"s,omitempty"`
+	Direction SortDirection `json:"d,omitempty"`
+}
+
+// EncodeCursor turns a cursor into an opaque URL safe token.
+func EncodeCursor(cursor Cursor) string {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		panic(fmt.Sprintf("pagination: encode cursor: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a token produced by EncodeCursor.
+func DecodeCursor(token string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, errors.New("malformed cursor")
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return Cursor{}, errors.New("malformed cursor")
+	}
+	return cursor, nil
+}
+
+// CursorRequest asks for a keyset page. A nil Position requests the first
+// page; otherwise the sort order stored in the cursor wins over SortBy and
+// Direction so that a listing cannot change order halfway through.
+type CursorRequest struct {
+	Position  *Cursor
+	PageSize  int32
+	SortBy    string
+	Direction SortDirection
+}
+
+// CursorPage is a keyset page. Next and Prev are opaque cursors for the
+// neighbouring pages and are empty at either end of the listing.
+type CursorPage[T any] struct {
+	Items    []T    `json:"items"`
+	PageSize int32  `json:"pageSize"`
+	Next     string `json:"next,omitempty"`
+	Prev     string `json:"prev,omitempty"`
+}
@@ -0,0 +1,314 @@
+package provider
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	apperror "currency-exchange/internal/error"
+	"currency-exchange/internal/service"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// AggregateMedian publishes the median of the accepted quotes.
+	AggregateMedian = "median"
+	// AggregateWeightedMean publishes the mean of the accepted quotes
+	// weighted by provider priority.
+	AggregateWeightedMean = "weighted_mean"
+
+	DefaultAggregationWindow = 2 * time.Hour
+	// DefaultMinSources is the smallest number of quotes among which one
+	// can be told apart as an outlier.
+	DefaultMinSources = 3
+
+	// aggregateSource names the aggregator in the logs of its sink.
+	aggregateSource = "aggregate"
+)
+
+// DefaultMaxDeviation discards quotes more than 5% away from the consensus.
+var DefaultMaxDeviation = decimal.RequireFromString("0.05")
+
+type AggregatorConfig struct {
+	// Method is AggregateMedian or AggregateWeightedMean.
+	Method string
+	// Window is how long a quote counts towards the consensus after it was
+	// received.
+	Window time.Duration
+	// MaxDeviation is the largest relative distance from the median of all
+	// quotes at which a quote is still accepted, e.g. 0.05 for 5%. Zero
+	// accepts every quote.
+	MaxDeviation decimal.Decimal
+	// MinSources is how many quotes a pair needs before outliers are
+	// rejected by their distance from the median. With fewer quotes, two
+	// of which disagree by more than MaxDeviation, no quote can be singled
+	// out and only the quote of the highest priority provider is used.
+	MinSources int
+	// Priorities weight providers in a weighted mean and pick the quote used
+	// when quotes disagree; unlisted providers weigh 1, and ties go to the
+	// provider name sorting first.
+	Priorities map[string]int
+}
+
+// pairKey names a currency pair. Quotes are kept under the canonical
+// direction of their pair, with the codes in lexical order, so that
+// providers locked to different bases are compared: the ECB quotes against
+// EUR, the Bank of Russia against RUB and the National Bank of Belarus
+// against BYN, so two of them share a pair only in opposite directions,
+// like RUB/BYN and BYN/RUB.
+type pairKey struct {
+	base   string
+	target string
+}
+
+// canonicalPair returns the key quotes of base/target are kept under and
+// whether their rates are inverted in it.
+func canonicalPair(base string, target string) (pairKey, bool) {
+	if base > target {
+		return pairKey{base: target, target: base}, true
+	}
+	return pairKey{base: base, target: target}, false
+}
+
+type receivedQuote struct {
+	rate       decimal.Decimal
+	receivedAt time.Time
+}
+
+// Aggregator sits between the providers and the rate book. It keeps the
+// latest quote of every provider per pair and, whenever a provider reports,
+// publishes one consensus rate per pair it quoted, in the direction it was
+// quoted, built from the quotes of all providers for either direction of the
+// pair received within the window. The accepted providers are passed on as
+// the sources of the rate.
+type Aggregator struct {
+	sink   RateSink
+	config AggregatorConfig
+
+	mu     sync.Mutex
+	quotes map[pairKey]map[string]receivedQuote
+}
+
+func NewAggregator(sink RateSink, config AggregatorConfig) (*Aggregator, error) {
+	if config.Method == "" {
+		config.Method = AggregateMedian
+	}
+	if config.Method != AggregateMedian && config.Method != AggregateWeightedMean {
+		return nil, apperror.Validation(
+			"invalid aggregation method",
+			"method must be "+AggregateMedian+" or "+AggregateWeightedMean,
+		)
+	}
+	if config.Window <= 0 {
+		config.Window = DefaultAggregationWindow
+	}
+	if config.MaxDeviation.IsNegative() {
+		return nil, apperror.Validation("invalid aggregation max deviation", "max deviation must not be negative")
+	}
+	if config.MinSources < 0 {
+		return nil, apperror.Validation("invalid aggregation min sources", "min sources must not be negative")
+	}
+	if config.MinSources == 0 {
+		config.MinSources = DefaultMinSources
+	}
+	for name, priority := range config.Priorities {
+		if priority < 1 {
+			return nil, apperror.Validation("invalid provider priority", name+": priority must be positive")
+		}
+	}
+	return &Aggregator{
+		sink:   sink,
+		config: config,
+		quotes: make(map[pairKey]map[string]receivedQuote),
+	}, nil
+}
+
+// RefreshRates records the quotes of source and hands the consensus of every
+// quoted pair to the sink. Quotes without a positive rate are reported as
+// skipped.
+func (a *Aggregator) RefreshRates(source string, quotes []service.RateQuote) (service.RefreshResult, error) {
+	log.Printf("aggregator.refresh_rates start source=%s quotes=%d", source, len(quotes))
+	now := time.Now().UTC()
+
+	// The lock is held across the write so that consensus rates reach the
+	// sink in the order they were built.
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var (
+		valid   []service.RateQuote
+		invalid []string
+	)
+	for _, quote := range quotes {
+		if !quote.Rate.IsPositive() {
+			invalid = append(invalid, quote.Base+"/"+quote.Target)
+			continue
+		}
+		valid = append(valid, quote)
+		key, inverted := canonicalPair(quote.Base, quote.Target)
+		rate := quote.Rate
+		if inverted {
+			rate = decimal.NewFromInt(1).Div(rate)
+		}
+		if a.quotes[key] == nil {
+			a.quotes[key] = make(map[string]receivedQuote)
+		}
+		a.quotes[key][source] = receivedQuote{rate: rate, receivedAt: now}
+	}
+	pairs := quotedPairs(valid)
+	published := make([]service.RateQuote, 0, len(pairs))
+	for _, pair := range pairs {
+		key, inverted := canonicalPair(pair.base, pair.target)
+		rate, sources := a.consensus(key, now)
+		if inverted {
+			rate = decimal.NewFromInt(1).Div(rate)
+		}
+		published = append(published, service.RateQuote{Base: pair.base, Target: pair.target, Rate: rate, Sources: sources})
+	}
+
+	result, err := a.sink.RefreshRates(aggregateSource, published)
+	if err != nil {
+		log.Printf("aggregator.refresh_rates error source=%s: %v", source, err)
+		return service.RefreshResult{}, err
+	}
+	result.Skipped = append(result.Skipped, invalid...)
+	log.Printf("aggregator.refresh_rates ok source=%s published=%d invalid=%d", source, len(published), len(invalid))
+	return result, nil
+}
+
+// consensus builds the rate of a pair in its canonical direction from the
+// quotes received within the window and returns it with the accepted
+// sources. Expired quotes are dropped. With at least MinSources quotes,
+// outliers are dropped by their distance from the median; with fewer, or
+// when every quote was an outlier, quotes disagreeing by more than
+// MaxDeviation are settled in favour of the preferred provider. The pair
+// must hold at least one unexpired quote.
+func (a *Aggregator) consensus(key pairKey, now time.Time) (decimal.Decimal, []string) {
+	sources := make([]string, 0, len(a.quotes[key]))
+	for source, quote := range a.quotes[key] {
+		if now.Sub(quote.receivedAt) > a.config.Window {
+			delete(a.quotes[key], source)
+			continue
+		}
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	rates := make([]decimal.Decimal, 0, len(sources))
+	for _, source := range sources {
+		rates = append(rates, a.quotes[key][source].rate)
+	}
+	reference := median(rates)
+
+	accepted := sources
+	if !a.config.MaxDeviation.IsZero() && reference.IsPositive() {
+		if len(sources) < a.config.MinSources {
+			spread := maxRate(rates).Sub(minRate(rates)).Div(reference)
+			if spread.GreaterThan(a.config.MaxDeviation) {
+				accepted = []string{a.preferredSource(sources)}
+				log.Printf("aggregator.consensus disagreement pair=%s/%s sources=%d spread=%s preferred=%s",
+					key.base, key.target, len(sources), spread.StringFixed(4), accepted[0])
+			}
+		} else {
+			accepted = nil
+			for _, source := range sources {
+				deviation := a.quotes[key][source].rate.Sub(reference).Abs().Div(reference)
+				if deviation.GreaterThan(a.config.MaxDeviation) {
+					log.Printf("aggregator.consensus outlier pair=%s/%s source=%s deviation=%s",
+						key.base, key.target, source, deviation.StringFixed(4))
+					continue
+				}
+				accepted = append(accepted, source)
+			}
+			if len(accepted) == 0 {
+				accepted = []string{a.preferredSource(sources)}
+				log.Printf("aggregator.consensus no_agreement pair=%s/%s preferred=%s", key.base, key.target, accepted[0])
+			}
+		}
+	}
+
+	if a.config.Method == AggregateWeightedMean {
+		return a.weightedMean(key, accepted), accepted
+	}
+	acceptedRates := make([]decimal.Decimal, 0, len(accepted))
+	for _, source := range accepted {
+		acceptedRates = append(acceptedRates, a.quotes[key][source].rate)
+	}
+	return median(acceptedRates), accepted
+}
+
+// preferredSource returns the source of the highest priority, the first by
+// name among equals; sources must be sorted.
+func (a *Aggregator) preferredSource(sources []string) string {
+	preferred, best := "", 0
+	for _, source := range sources {
+		priority := a.priority(source)
+		if preferred == "" || priority > best {
+			preferred, best = source, priority
+		}
+	}
+	return preferred
+}
+
+func (a *Aggregator) priority(source string) int {
+	if priority, ok := a.config.Priorities[source]; ok {
+		return priority
+	}
+	return 1
+}
+
+func (a *Aggregator) weightedMean(key pairKey, sources []string) decimal.Decimal {
+	sum, weights := decimal.Zero, decimal.Zero
+	for _, source := range sources {
+		weight := decimal.NewFromInt(int64(a.priority(source)))
+		sum = sum.Add(a.quotes[key][source].rate.Mul(weight))
+		weights = weights.Add(weight)
+	}
+	return sum.Div(weights)
+}
+
+// median returns the middle rate, or the mean of the two middle rates of an
+// even count; zero for no rates.
+func median(rates []decimal.Decimal) decimal.Decimal {
+	if len(rates) == 0 {
+		return decimal.Zero
+	}
+	sorted := append([]decimal.Decimal{}, rates...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].LessThan(sorted[j]) })
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[middle]
+	}
+	return sorted[middle-1].Add(sorted[middle]).Div(decimal.NewFromInt(2))
+}
+
+func minRate(rates []decimal.Decimal) decimal.Decimal {
+	lowest := rates[0]
+	for _, rate := range rates[1:] {
+		lowest = decimal.Min(lowest, rate)
+	}
+	return lowest
+}
+
+func maxRate(rates []decimal.Decimal) decimal.Decimal {
+	highest := rates[0]
+	for _, rate := range rates[1:] {
+		highest = decimal.Max(highest, rate)
+	}
+	return highest
+}
+
+// quotedPairs lists the distinct pairs of quotes in order of appearance.
+func quotedPairs(quotes []service.RateQuote) []pairKey {
+	seen := make(map[pairKey]bool, len(quotes))
+	pairs := make([]pairKey, 0, len(quotes))
+	for _, quote := range quotes {
+		key := pairKey{base: quote.Base, target: quote.Target}
+		if !seen[key] {
+			seen[key] = true
+			pairs = append(pairs, key)
+		}
+	}
+	return pairs
+}
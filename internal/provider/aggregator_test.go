@@ -0,0 +1,242 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"currency-exchange/internal/service"
+
+	"github.com/shopspring/decimal"
+)
+
+// recordingSink keeps the quotes of the latest refresh.
+type recordingSink struct {
+	source string
+	quotes []service.RateQuote
+}
+
+func (s *recordingSink) RefreshRates(source string, quotes []service.RateQuote) (service.RefreshResult, error) {
+	s.source, s.quotes = source, quotes
+	return service.RefreshResult{Written: len(quotes)}, nil
+}
+
+type sourceQuote struct {
+	source string
+	base   string
+	target string
+	rate   string
+}
+
+// aggregate feeds the quotes to a new aggregator one source at a time and
+// returns what the last refresh published.
+func aggregate(t *testing.T, config AggregatorConfig, quotes []sourceQuote) []service.RateQuote {
+	t.Helper()
+	sink := &recordingSink{}
+	aggregator, err := NewAggregator(sink, config)
+	if err != nil {
+		t.Fatalf("NewAggregator() error = %v", err)
+	}
+	for _, quote := range quotes {
+		_, err := aggregator.RefreshRates(quote.source, []service.RateQuote{
+			{Base: quote.base, Target: quote.target, Rate: decimal.RequireFromString(quote.rate)},
+		})
+		if err != nil {
+			t.Fatalf("RefreshRates() error = %v", err)
+		}
+	}
+	if sink.source != aggregateSource {
+		t.Fatalf("sink source = %q, want %q", sink.source, aggregateSource)
+	}
+	return sink.quotes
+}
+
+func TestAggregatorConsensus(t *testing.T) {
+	fivePercent := decimal.RequireFromString("0.05")
+	tests := []struct {
+		name        string
+		config      AggregatorConfig
+		quotes      []sourceQuote
+		wantPair    string
+		wantRate    string
+		wantSources []string
+	}{
+		{
+			name:   "median of an odd count",
+			config: AggregatorConfig{},
+			quotes: []sourceQuote{
+				{source: "a", base: "EUR", target: "USD", rate: "1.10"},
+				{source: "c", base: "EUR", target: "USD", rate: "1.30"},
+				{source: "b", base: "EUR", target: "USD", rate: "1.20"},
+			},
+			wantPair:    "EUR/USD",
+			wantRate:    "1.2",
+			wantSources: []string{"a", "b", "c"},
+		},
+		{
+			name:   "median of an even count",
+			config: AggregatorConfig{},
+			quotes: []sourceQuote{
+				{source: "a", base: "EUR", target: "USD", rate: "1.10"},
+				{source: "b", base: "EUR", target: "USD", rate: "1.20"},
+				{source: "c", base: "EUR", target: "USD", rate: "1.25"},
+				{source: "d", base: "EUR", target: "USD", rate: "1.40"},
+			},
+			wantPair:    "EUR/USD",
+			wantRate:    "1.225",
+			wantSources: []string{"a", "b", "c", "d"},
+		},
+		{
+			name:   "weighted mean by priority",
+			config: AggregatorConfig{Method: AggregateWeightedMean, Priorities: map[string]int{"b": 2}},
+			quotes: []sourceQuote{
+				{source: "a", base: "EUR", target: "USD", rate: "100"},
+				{source: "b", base: "EUR", target: "USD", rate: "103"},
+			},
+			wantPair:    "EUR/USD",
+			wantRate:    "102",
+			wantSources: []string{"a", "b"},
+		},
+		{
+			name:   "outlier dropped once the quorum is met",
+			config: AggregatorConfig{MaxDeviation: fivePercent, MinSources: 3},
+			quotes: []sourceQuote{
+				{source: "a", base: "EUR", target: "USD", rate: "100"},
+				{source: "b", base: "EUR", target: "USD", rate: "101"},
+				{source: "c", base: "EUR", target: "USD", rate: "150"},
+			},
+			wantPair:    "EUR/USD",
+			wantRate:    "100.5",
+			wantSources: []string{"a", "b"},
+		},
+		{
+			name:   "agreeing quotes below the quorum are all kept",
+			config: AggregatorConfig{MaxDeviation: fivePercent, MinSources: 3},
+			quotes: []sourceQuote{
+				{source: "a", base: "EUR", target: "USD", rate: "100"},
+				{source: "b", base: "EUR", target: "USD", rate: "102"},
+			},
+			wantPair:    "EUR/USD",
+			wantRate:    "101",
+			wantSources: []string{"a", "b"},
+		},
+		{
+			name:   "disagreement below the quorum falls back to the highest priority",
+			config: AggregatorConfig{MaxDeviation: fivePercent, MinSources: 3, Priorities: map[string]int{"b": 2}},
+			quotes: []sourceQuote{
+				{source: "a", base: "EUR", target: "USD", rate: "100"},
+				{source: "b", base: "EUR", target: "USD", rate: "120"},
+			},
+			wantPair:    "EUR/USD",
+			wantRate:    "120",
+			wantSources: []string{"b"},
+		},
+		{
+			name:   "disagreement between equal priorities falls back to the first name",
+			config: AggregatorConfig{MaxDeviation: fivePercent},
+			quotes: []sourceQuote{
+				{source: "b", base: "EUR", target: "USD", rate: "120"},
+				{source: "a", base: "EUR", target: "USD", rate: "100"},
+			},
+			wantPair:    "EUR/USD",
+			wantRate:    "100",
+			wantSources: []string{"a"},
+		},
+		{
+			name:   "inverse quote folded into the canonical pair",
+			config: AggregatorConfig{},
+			quotes: []sourceQuote{
+				{source: "ecb", base: "EUR", target: "RUB", rate: "75"},
+				{source: "cbr", base: "RUB", target: "EUR", rate: "0.008"},
+			},
+			wantPair:    "RUB/EUR",
+			wantRate:    "0.01",
+			wantSources: []string{"cbr", "ecb"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			published := aggregate(t, tt.config, tt.quotes)
+			if len(published) != 1 {
+				t.Fatalf("published %d quotes, want 1", len(published))
+			}
+			quote := published[0]
+			if pair := quote.Base + "/" + quote.Target; pair != tt.wantPair {
+				t.Errorf("pair = %s, want %s", pair, tt.wantPair)
+			}
+			if !quote.Rate.Equal(decimal.RequireFromString(tt.wantRate)) {
+				t.Errorf("rate = %s, want %s", quote.Rate, tt.wantRate)
+			}
+			if !reflect.DeepEqual(quote.Sources, tt.wantSources) {
+				t.Errorf("sources = %v, want %v", quote.Sources, tt.wantSources)
+			}
+		})
+	}
+}
+
+func TestAggregatorDropsExpiredQuotes(t *testing.T) {
+	sink := &recordingSink{}
+	aggregator, err := NewAggregator(sink, AggregatorConfig{Window: time.Hour})
+	if err != nil {
+		t.Fatalf("NewAggregator() error = %v", err)
+	}
+	if _, err := aggregator.RefreshRates("a", []service.RateQuote{
+		{Base: "EUR", Target: "USD", Rate: decimal.RequireFromString("1.00")},
+	}); err != nil {
+		t.Fatalf("RefreshRates() error = %v", err)
+	}
+	key, _ := canonicalPair("EUR", "USD")
+	expired := aggregator.quotes[key]["a"]
+	expired.receivedAt = expired.receivedAt.Add(-2 * time.Hour)
+	aggregator.quotes[key]["a"] = expired
+
+	if _, err := aggregator.RefreshRates("b", []service.RateQuote{
+		{Base: "EUR", Target: "USD", Rate: decimal.RequireFromString("1.20")},
+	}); err != nil {
+		t.Fatalf("RefreshRates() error = %v", err)
+	}
+	if len(sink.quotes) != 1 || !sink.quotes[0].Rate.Equal(decimal.RequireFromString("1.20")) ||
+		!reflect.DeepEqual(sink.quotes[0].Sources, []string{"b"}) {
+		t.Errorf("published %+v, want only the quote of b", sink.quotes)
+	}
+}
+
+func TestAggregatorSkipsNonPositiveQuotes(t *testing.T) {
+	sink := &recordingSink{}
+	aggregator, err := NewAggregator(sink, AggregatorConfig{})
+	if err != nil {
+		t.Fatalf("NewAggregator() error = %v", err)
+	}
+	result, err := aggregator.RefreshRates("a", []service.RateQuote{
+		{Base: "RUB", Target: "EUR", Rate: decimal.Zero},
+		{Base: "EUR", Target: "USD", Rate: decimal.RequireFromString("1.10")},
+	})
+	if err != nil {
+		t.Fatalf("RefreshRates() error = %v", err)
+	}
+	if len(sink.quotes) != 1 || sink.quotes[0].Target != "USD" {
+		t.Errorf("published %+v, want only EUR/USD", sink.quotes)
+	}
+	if !reflect.DeepEqual(result.Skipped, []string{"RUB/EUR"}) {
+		t.Errorf("skipped = %v, want [RUB/EUR]", result.Skipped)
+	}
+}
+
+func TestCanonicalPair(t *testing.T) {
+	tests := []struct {
+		base, target string
+		want         pairKey
+		wantInverted bool
+	}{
+		{base: "EUR", target: "RUB", want: pairKey{base: "EUR", target: "RUB"}},
+		{base: "RUB", target: "EUR", want: pairKey{base: "EUR", target: "RUB"}, wantInverted: true},
+		{base: "BYN", target: "RUB", want: pairKey{base: "BYN", target: "RUB"}},
+		{base: "RUB", target: "BYN", want: pairKey{base: "BYN", target: "RUB"}, wantInverted: true},
+	}
+	for _, tt := range tests {
+		key, inverted := canonicalPair(tt.base, tt.target)
+		if key != tt.want || inverted != tt.wantInverted {
+			t.Errorf("canonicalPair(%s, %s) = %v, %t; want %v, %t", tt.base, tt.target, key, inverted, tt.want, tt.wantInverted)
+		}
+	}
+}
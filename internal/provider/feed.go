@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"context"
+
+	apperror "currency-exchange/internal/error"
+	"currency-exchange/internal/importer"
+	"currency-exchange/internal/service"
+)
+
+// FeedProvider polls a central bank feed. A feed publishes against one
+// currency only, so Fetch rejects any other base.
+type FeedProvider struct {
+	Feed importer.Feed
+	// Base is the currency the feed publishes against.
+	Base string
+}
+
+func (p FeedProvider) Name() string {
+	return p.Feed.Name()
+}
+
+// Fetch returns the quotes of the latest publication day in the feed.
+func (p FeedProvider) Fetch(ctx context.Context, base string) ([]service.RateQuote, error) {
+	if base != p.Base {
+		return nil, apperror.Validation(
+			"unsupported base currency",
+			p.Feed.Name()+" publishes rates against "+p.Base+" only",
+		)
+	}
+	quotes, err := p.Feed.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest importer.Quote
+	for _, quote := range quotes {
+		if quote.Date.After(latest.Date) {
+			latest = quote
+		}
+	}
+	result := make([]service.RateQuote, 0, len(quotes))
+	for _, quote := range quotes {
+		if quote.Date.Equal(latest.Date) {
+			result = append(result, service.RateQuote{Base: quote.Base, Target: quote.Target, Rate: quote.Rate})
+		}
+	}
+	return result, nil
+}
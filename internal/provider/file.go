@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"sort"
+
+	apperror "currency-exchange/internal/error"
+	"currency-exchange/internal/service"
+
+	"github.com/shopspring/decimal"
+)
+
+// FileProvider serves quotes from a local JSON file mapping base currencies
+// to target rates, e.g. {"USD": {"EUR": 0.92, "GBP": 0.78}}. The file is read
+// on every fetch, so editing it changes the next poll; it stands in for a
+// real provider where there is no network.
+type FileProvider struct {
+	Path string
+}
+
+func (p FileProvider) Name() string {
+	return "file"
+}
+
+func (p FileProvider) Fetch(ctx context.Context, base string) ([]service.RateQuote, error) {
+	log.Printf("file_provider.fetch start path=%s base=%s", p.Path, base)
+	content, err := os.ReadFile(p.Path)
+	if err != nil {
+		log.Printf("file_provider.fetch error: %v", err)
+		return nil, apperror.Internal("read rate file", err.Error())
+	}
+	var book map[string]map[string]decimal.Decimal
+	if err := json.Unmarshal(content, &book); err != nil {
+		log.Printf("file_provider.fetch parse_error: %v", err)
+		return nil, apperror.Validation("invalid rate file", err.Error())
+	}
+	rates, ok := book[base]
+	if !ok {
+		log.Printf("file_provider.fetch not_found base=%s", base)
+		return nil, apperror.NotFound("base currency not in rate file", "base="+base)
+	}
+
+	targets := make([]string, 0, len(rates))
+	for target := range rates {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	quotes := make([]service.RateQuote, 0, len(targets))
+	for _, target := range targets {
+		quotes = append(quotes, service.RateQuote{Base: base, Target: target, Rate: rates[target]})
+	}
+	log.Printf("file_provider.fetch ok quotes=%d", len(quotes))
+	return quotes, nil
+}
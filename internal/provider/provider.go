@@ -0,0 +1,79 @@
+// Package provider polls rate providers on a schedule and hands their quotes
+// to the rate book.
+package provider
+
+import (
+	"context"
+	"time"
+
+	apperror "currency-exchange/internal/error"
+	"currency-exchange/internal/service"
+)
+
+// RateProvider is a source of current exchange rates.
+type RateProvider interface {
+	Name() string
+	// Fetch returns the quotes of base against every currency the provider
+	// knows.
+	Fetch(ctx context.Context, base string) ([]service.RateQuote, error)
+}
+
+// RateSink receives the quotes of every successful poll.
+// *service.ExchangeService is the usual sink.
+type RateSink interface {
+	RefreshRates(source string, quotes []service.RateQuote) (service.RefreshResult, error)
+}
+
+// Schedule says how a registered provider is polled.
+type Schedule struct {
+	// Base is the currency whose quotes are fetched.
+	Base string
+	// Interval separates successful polls.
+	Interval time.Duration
+	// Timeout bounds a single fetch.
+	Timeout time.Duration
+}
+
+type registration struct {
+	provider RateProvider
+	schedule Schedule
+}
+
+// Registry holds the configured providers by name.
+type Registry struct {
+	registrations []registration
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a provider. Names must be unique and the interval and
+// timeout positive.
+func (r *Registry) Register(provider RateProvider, schedule Schedule) error {
+	for _, existing := range r.registrations {
+		if existing.provider.Name() == provider.Name() {
+			return apperror.Conflict("rate provider already registered", "name="+provider.Name())
+		}
+	}
+	if schedule.Base == "" {
+		return apperror.Validation("invalid rate provider schedule", provider.Name()+": base is required")
+	}
+	if schedule.Interval <= 0 || schedule.Timeout <= 0 {
+		return apperror.Validation(
+			"invalid rate provider schedule",
+			provider.Name()+": interval and timeout must be positive",
+		)
+	}
+	r.registrations = append(r.registrations, registration{provider: provider, schedule: schedule})
+	return nil
+}
+
+// Names lists the registered providers in registration order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.registrations))
+	for _, registration := range r.registrations {
+		names = append(names, registration.provider.Name())
+	}
+	return names
+}
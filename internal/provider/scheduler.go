@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"currency-exchange/internal/dto"
+	"currency-exchange/internal/service"
+)
+
+const (
+	DefaultMinBackoff = 10 * time.Second
+	DefaultMaxBackoff = 15 * time.Minute
+)
+
+// SchedulerConfig bounds the retry delay after failed polls. The delay
+// doubles with every consecutive failure from MinBackoff up to MaxBackoff
+// and is jittered so that providers failing together do not retry together.
+type SchedulerConfig struct {
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Scheduler polls every registered provider on its own schedule and writes
+// the quotes into the sink.
+type Scheduler struct {
+	registry *Registry
+	sink     RateSink
+	config   SchedulerConfig
+
+	mu       sync.Mutex
+	statuses map[string]*dto.ProviderStatusDto
+}
+
+func NewScheduler(registry *Registry, sink RateSink, config SchedulerConfig) *Scheduler {
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = DefaultMinBackoff
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = max(DefaultMaxBackoff, config.MinBackoff)
+	}
+	statuses := make(map[string]*dto.ProviderStatusDto, len(registry.registrations))
+	for _, registration := range registry.registrations {
+		statuses[registration.provider.Name()] = &dto.ProviderStatusDto{
+			Name:     registration.provider.Name(),
+			Base:     registration.schedule.Base,
+			Interval: registration.schedule.Interval.String(),
+			Skipped:  []string{},
+			Guarded:  []string{},
+		}
+	}
+	return &Scheduler{
+		registry: registry,
+		sink:     sink,
+		config:   config,
+		statuses: statuses,
+	}
+}
+
+// Start polls every provider right away and then on its schedule until ctx
+// is cancelled. It does not block.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, registration := range s.registry.registrations {
+		go s.run(ctx, registration)
+	}
+}
+
+// Statuses returns a snapshot of every provider's status in registration
+// order.
+func (s *Scheduler) Statuses() []dto.ProviderStatusDto {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]dto.ProviderStatusDto, 0, len(s.statuses))
+	for _, name := range s.registry.Names() {
+		status := *s.statuses[name]
+		status.Skipped = append([]string{}, status.Skipped...)
+		status.Guarded = append([]string{}, status.Guarded...)
+		result = append(result, status)
+	}
+	return result
+}
+
+func (s *Scheduler) run(ctx context.Context, registration registration) {
+	failures := 0
+	for {
+		delay := registration.schedule.Interval
+		if err := s.poll(ctx, registration); err != nil {
+			failures++
+			delay = s.backoff(failures)
+		} else {
+			failures = 0
+		}
+		next := time.Now().UTC().Add(delay)
+		s.update(registration.provider.Name(), func(status *dto.ProviderStatusDto) {
+			status.NextRun = &next
+		})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// poll fetches one set of quotes within the provider's timeout and writes
+// them, recording the outcome in the provider's status.
+func (s *Scheduler) poll(ctx context.Context, registration registration) error {
+	name := registration.provider.Name()
+	log.Printf("scheduler.poll start provider=%s base=%s", name, registration.schedule.Base)
+	started := time.Now().UTC()
+	s.update(name, func(status *dto.ProviderStatusDto) {
+		status.LastRun = &started
+	})
+
+	fetchCtx, cancel := context.WithTimeout(ctx, registration.schedule.Timeout)
+	quotes, err := registration.provider.Fetch(fetchCtx, registration.schedule.Base)
+	cancel()
+	if err == nil {
+		var result service.RefreshResult
+		result, err = s.sink.RefreshRates(name, quotes)
+		if err == nil {
+			finished := time.Now().UTC()
+			s.update(name, func(status *dto.ProviderStatusDto) {
+				status.LastSuccess = &finished
+				status.Failures = 0
+				status.Written = result.Written
+				status.Skipped = append([]string{}, result.Skipped...)
+				status.Guarded = append([]string{}, result.Guarded...)
+			})
+			log.Printf("scheduler.poll ok provider=%s written=%d", name, result.Written)
+			return nil
+		}
+	}
+
+	failed := time.Now().UTC()
+	s.update(name, func(status *dto.ProviderStatusDto) {
+		status.LastError = err.Error()
+		status.LastErrorAt = &failed
+		status.Failures++
+	})
+	log.Printf("scheduler.poll error provider=%s: %v", name, err)
+	return err
+}
+
+func (s *Scheduler) update(name string, change func(status *dto.ProviderStatusDto)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change(s.statuses[name])
+}
+
+// backoff returns the jittered delay before the retry that follows the
+// given number of consecutive failures: a random duration between half and
+// all of the exponential delay.
+func (s *Scheduler) backoff(failures int) time.Duration {
+	delay := s.config.MinBackoff
+	for i := 1; i < failures && delay < s.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, s.config.MaxBackoff)
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(delay-half)+1))
+}
@@ -6,8 +6,36 @@ import (
 	"currency-exchange/internal/pagination"
 )
 
+const (
+	CurrencySortID   = "id"
+	CurrencySortCode = "code"
+	CurrencySortName = "name"
+)
+
+// CurrencyFilter narrows a currency listing. Zero values do not filter.
+type CurrencyFilter struct {
+	// Search matches a code prefix or a substring of the full name, ignoring
+	// case.
+	Search string
+	// Codes restricts the listing to the given codes.
+	Codes []string
+	// Active selects ISO 4217 active or withdrawn currencies; nil lists both.
+	Active *bool
+	// Disabled selects currencies by their operator controlled state; nil
+	// lists both.
+	Disabled *bool
+}
+
 type CurrencyRepository interface {
 	Create(ctx context.Context, currency entity.Currency) (int64, error)
+	Update(ctx context.Context, currency entity.Currency) error
+	Delete(ctx context.Context, code string, force bool) error
+	Upsert(ctx context.Context, currencies []entity.Currency, updateExisting bool) (inserted []string, updated []string, err error)
+	// CreateAll inserts every currency in one transaction; a code that
+	// already exists fails the whole batch with a conflict.
+	CreateAll(ctx context.Context, currencies []entity.Currency) error
 	GetByCode(ctx context.Context, code string) (entity.Currency, error)
-	GetPage(ctx context.Context, page pagination.PageRequest) (pagination.Page[entity.Currency], error)
+	GetAll(ctx context.Context) ([]entity.Currency, error)
+	GetPage(ctx context.Context, filter CurrencyFilter, page pagination.PageRequest) (pagination.Page[entity.Currency], error)
+	GetCursorPage(ctx context.Context, filter CurrencyFilter, page pagination.CursorRequest) (pagination.CursorPage[entity.Currency], error)
 }
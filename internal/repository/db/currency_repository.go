@@ -9,8 +9,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 
 	"currency-exchange/internal/entity"
+
+	"github.com/lib/pq"
 )
 
 type CurrencyRepositoryDB struct {
@@ -27,12 +30,17 @@ func (r *CurrencyRepositoryDB) Create(ctx context.Context, currency entity.Curre
 	log.Printf("currency_repository.create start code=%s", currency.Code)
 	row := r.db.QueryRowContext(
 		ctx,
-		`INSERT INTO currencies (code, full_name, sign)
-		 VALUES ($1, $2, $3)
+		`INSERT INTO currencies (code, full_name, sign, minor_units, numeric_code, countries, active, disabled)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		 RETURNING id`,
 		currency.Code,
 		currency.FullName,
 		currency.Sign,
+		currency.MinorUnits,
+		currency.NumericCode,
+		pq.Array(currency.Countries),
+		currency.Active,
+		currency.Disabled,
 	)
 
 	var id int64
@@ -45,11 +53,216 @@ func (r *CurrencyRepositoryDB) Create(ctx context.Context, currency entity.Curre
 	return id, nil
 }
 
+func (r *CurrencyRepositoryDB) Update(ctx context.Context, currency entity.Currency) error {
+	log.Printf("currency_repository.update start code=%s", currency.Code)
+	result, err := r.db.ExecContext(
+		ctx,
+		`UPDATE currencies
+		 SET full_name = $1,
+		     sign = $2,
+		     minor_units = $3,
+		     numeric_code = $4,
+		     countries = $5,
+		     active = $6,
+		     disabled = $7
+		 WHERE code = $8`,
+		currency.FullName,
+		currency.Sign,
+		currency.MinorUnits,
+		currency.NumericCode,
+		pq.Array(currency.Countries),
+		currency.Active,
+		currency.Disabled,
+		currency.Code,
+	)
+	if err != nil {
+		log.Printf("currency_repository.update error: %v", err)
+		return apperror.Internal("db update currency", err.Error())
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("currency_repository.update rows_affected_error: %v", err)
+		return apperror.Internal("db check currency update", err.Error())
+	}
+	if affected == 0 {
+		log.Printf("currency_repository.update not_found code=%s", currency.Code)
+		return apperror.NotFound("currency not found", "code="+currency.Code)
+	}
+
+	log.Printf("currency_repository.update ok code=%s", currency.Code)
+	return nil
+}
+
+// Delete removes a currency. Unless force is set it refuses with a conflict
+// while exchange rates or rate history still reference the currency, because
+// the foreign keys would cascade and silently drop them.
+func (r *CurrencyRepositoryDB) Delete(ctx context.Context, code string, force bool) error {
+	log.Printf("currency_repository.delete start code=%s force=%t", code, force)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("currency_repository.delete begin_error: %v", err)
+		return apperror.Internal("db begin currency delete", err.Error())
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM currencies WHERE code = $1 FOR UPDATE`, code).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Printf("currency_repository.delete not_found code=%s", code)
+			return apperror.NotFound("currency not found", "code="+code)
+		}
+		log.Printf("currency_repository.delete lock_error: %v", err)
+		return apperror.Internal("db lock currency", err.Error())
+	}
+
+	if !force {
+		var rates, history int
+		err = tx.QueryRowContext(
+			ctx,
+			`SELECT
+			   (SELECT COUNT(*) FROM exchange_rates WHERE base_currency_id = $1 OR target_currency_id = $1),
+			   (SELECT COUNT(*) FROM exchange_rate_history WHERE base_currency_id = $1 OR target_currency_id = $1)`,
+			id,
+		).Scan(&rates, &history)
+		if err != nil {
+			log.Printf("currency_repository.delete count_error: %v", err)
+			return apperror.Internal("db count currency references", err.Error())
+		}
+		if rates > 0 || history > 0 {
+			log.Printf("currency_repository.delete conflict code=%s rates=%d history=%d", code, rates, history)
+			return apperror.Conflict(
+				"currency is in use",
+				fmt.Sprintf("code=%s rates=%d history=%d", code, rates, history),
+			)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM currencies WHERE id = $1`, id); err != nil {
+		log.Printf("currency_repository.delete error: %v", err)
+		return apperror.Internal("db delete currency", err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("currency_repository.delete commit_error: %v", err)
+		return apperror.Internal("db commit currency delete", err.Error())
+	}
+
+	log.Printf("currency_repository.delete ok code=%s", code)
+	return nil
+}
+
+func (r *CurrencyRepositoryDB) CreateAll(ctx context.Context, currencies []entity.Currency) error {
+	log.Printf("currency_repository.create_all start count=%d", len(currencies))
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("currency_repository.create_all begin_error: %v", err)
+		return apperror.Internal("db begin create currencies", err.Error())
+	}
+	defer tx.Rollback()
+
+	for _, currency := range currencies {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO currencies (code, full_name, sign, minor_units, numeric_code, countries, active, disabled)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			currency.Code,
+			currency.FullName,
+			currency.Sign,
+			currency.MinorUnits,
+			currency.NumericCode,
+			pq.Array(currency.Countries),
+			currency.Active,
+			currency.Disabled,
+		); err != nil {
+			if isUniqueViolation(err) {
+				log.Printf("currency_repository.create_all conflict code=%s", currency.Code)
+				return apperror.Conflict("currency already exists", "code="+currency.Code)
+			}
+			log.Printf("currency_repository.create_all error code=%s: %v", currency.Code, err)
+			return apperror.Internal("db create currency", err.Error())
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("currency_repository.create_all commit_error: %v", err)
+		return apperror.Internal("db commit create currencies", err.Error())
+	}
+
+	log.Printf("currency_repository.create_all ok count=%d", len(currencies))
+	return nil
+}
+
+// Upsert inserts the currencies whose codes are missing in one transaction.
+// With updateExisting it also refreshes the ISO 4217 owned columns (minor
+// units, numeric code, countries, active) of currencies that already exist,
+// leaving the locally maintained full name, sign and disabled state
+// untouched.
+func (r *CurrencyRepositoryDB) Upsert(ctx context.Context, currencies []entity.Currency, updateExisting bool) ([]string, []string, error) {
+	log.Printf("currency_repository.upsert start count=%d update_existing=%t", len(currencies), updateExisting)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("currency_repository.upsert begin_error: %v", err)
+		return nil, nil, apperror.Internal("db begin upsert currencies", err.Error())
+	}
+	defer tx.Rollback()
+
+	conflict := `ON CONFLICT (code) DO NOTHING`
+	if updateExisting {
+		conflict = `ON CONFLICT (code) DO UPDATE
+		 SET minor_units = EXCLUDED.minor_units,
+		     numeric_code = EXCLUDED.numeric_code,
+		     countries = EXCLUDED.countries,
+		     active = EXCLUDED.active
+		 WHERE (currencies.minor_units, currencies.numeric_code, currencies.countries, currencies.active)
+		       IS DISTINCT FROM (EXCLUDED.minor_units, EXCLUDED.numeric_code, EXCLUDED.countries, EXCLUDED.active)`
+	}
+
+	var inserted, updated []string
+	for _, currency := range currencies {
+		var isInsert bool
+		err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO currencies (code, full_name, sign, minor_units, numeric_code, countries, active, disabled)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			 `+conflict+`
+			 RETURNING xmax = 0`,
+			currency.Code,
+			currency.FullName,
+			currency.Sign,
+			currency.MinorUnits,
+			currency.NumericCode,
+			pq.Array(currency.Countries),
+			currency.Active,
+			currency.Disabled,
+		).Scan(&isInsert)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			log.Printf("currency_repository.upsert error code=%s: %v", currency.Code, err)
+			return nil, nil, apperror.Internal("db upsert currency", err.Error())
+		}
+		if isInsert {
+			inserted = append(inserted, currency.Code)
+		} else {
+			updated = append(updated, currency.Code)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("currency_repository.upsert commit_error: %v", err)
+		return nil, nil, apperror.Internal("db commit upsert currencies", err.Error())
+	}
+
+	log.Printf("currency_repository.upsert ok inserted=%d updated=%d", len(inserted), len(updated))
+	return inserted, updated, nil
+}
+
 func (r *CurrencyRepositoryDB) GetByID(ctx context.Context, id int64) (entity.Currency, error) {
 	log.Printf("currency_repository.get_by_id start id=%d", id)
 	row := r.db.QueryRowContext(
 		ctx,
-		`SELECT id, code, full_name, sign
+		`SELECT `+currencyColumns("")+`
 		 FROM currencies
 		 WHERE id = $1`,
 		id,
@@ -73,7 +286,7 @@ func (r *CurrencyRepositoryDB) GetByCode(ctx context.Context, code string) (enti
 	log.Printf("currency_repository.get_by_code start code=%s", code)
 	row := r.db.QueryRowContext(
 		ctx,
-		`SELECT id, code, full_name, sign
+		`SELECT `+currencyColumns("")+`
 		 FROM currencies
 		 WHERE code = $1`,
 		code,
@@ -97,7 +310,7 @@ func (r *CurrencyRepositoryDB) GetAll(ctx context.Context) ([]entity.Currency, e
 	log.Printf("currency_repository.get_all start")
 	rows, err := r.db.QueryContext(
 		ctx,
-		`SELECT id, code, full_name, sign
+		`SELECT `+currencyColumns("")+`
 		 FROM currencies
 		 ORDER BY id`,
 	)
@@ -126,8 +339,8 @@ func (r *CurrencyRepositoryDB) GetAll(ctx context.Context) ([]entity.Currency, e
 	return currencies, nil
 }
 
-func (r *CurrencyRepositoryDB) GetPage(ctx context.Context, page pagination.PageRequest) (pagination.Page[entity.Currency], error) {
-	log.Printf("currency_repository.get_page start page=%d size=%d", page.PageNumber, page.PageSize)
+func (r *CurrencyRepositoryDB) GetPage(ctx context.Context, filter repository.CurrencyFilter, page pagination.PageRequest) (pagination.Page[entity.Currency], error) {
+	log.Printf("currency_repository.get_page start page=%d size=%d search=%q codes=%d sort=%s %s", page.PageNumber, page.PageSize, filter.Search, len(filter.Codes), page.SortBy, page.Direction)
 	if page.PageNumber < 1 || page.PageSize < 1 {
 		log.Printf("currency_repository.get_page validation_error page=%d size=%d", page.PageNumber, page.PageSize)
 		return pagination.Page[entity.Currency]{}, apperror.Validation("invalid page params", fmt.Sprintf(
@@ -136,9 +349,16 @@ func (r *CurrencyRepositoryDB) GetPage(ctx context.Context, page pagination.Page
 			page.PageSize,
 		))
 	}
+	orderBy, err := currencyOrderBy(page)
+	if err != nil {
+		log.Printf("currency_repository.get_page validation_error: %v", err)
+		return pagination.Page[entity.Currency]{}, err
+	}
+	conditions, args := currencyFilterConditions(filter)
+	where := whereClause(conditions)
 
 	var total int
-	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM currencies`).Scan(&total); err != nil {
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM currencies`+where, args...).Scan(&total); err != nil {
 		log.Printf("currency_repository.get_page count_error: %v", err)
 		return pagination.Page[entity.Currency]{}, apperror.Internal("db count currencies", err.Error())
 	}
@@ -147,12 +367,11 @@ func (r *CurrencyRepositoryDB) GetPage(ctx context.Context, page pagination.Page
 	offset := int64(page.PageNumber-1) * int64(page.PageSize)
 	rows, err := r.db.QueryContext(
 		ctx,
-		`SELECT id, code, full_name, sign
-		 FROM currencies
-		 ORDER BY id
-		 LIMIT $1 OFFSET $2`,
-		limit,
-		offset,
+		`SELECT `+currencyColumns("")+`
+		 FROM currencies`+where+`
+		 ORDER BY `+orderBy+`
+		 LIMIT `+fmt.Sprintf("$%d OFFSET $%d", len(args)+1, len(args)+2),
+		append(args, limit, offset)...,
 	)
 	if err != nil {
 		log.Printf("currency_repository.get_page query_error: %v", err)
@@ -184,18 +403,186 @@ func (r *CurrencyRepositoryDB) GetPage(ctx context.Context, page pagination.Page
 	}, nil
 }
 
+// GetCursorPage returns the currencies after (or before) the requested
+// keyset position.
+func (r *CurrencyRepositoryDB) GetCursorPage(ctx context.Context, filter repository.CurrencyFilter, page pagination.CursorRequest) (pagination.CursorPage[entity.Currency], error) {
+	log.Printf("currency_repository.get_cursor_page start size=%d has_cursor=%t", page.PageSize, page.Position != nil)
+	if page.PageSize < 1 {
+		log.Printf("currency_repository.get_cursor_page validation_error size=%d", page.PageSize)
+		return pagination.CursorPage[entity.Currency]{}, apperror.Validation("invalid page params", fmt.Sprintf(
+			"invalid page params: pageSize=%d",
+			page.PageSize,
+		))
+	}
+
+	keys := newKeyset(page)
+	column, err := currencySortColumn(keys.sortBy)
+	if err != nil {
+		log.Printf("currency_repository.get_cursor_page validation_error: %v", err)
+		return pagination.CursorPage[entity.Currency]{}, err
+	}
+	keyColumn := column
+	if column == "id" {
+		keyColumn = ""
+	}
+
+	conditions, args := currencyFilterConditions(filter)
+	conditions, args, orderBy := keys.apply(conditions, args, keyColumn, "id")
+	args = append(args, int64(page.PageSize)+1)
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+currencyColumns("")+`
+		 FROM currencies`+whereClause(conditions)+`
+		 ORDER BY `+orderBy+`
+		 LIMIT `+fmt.Sprintf("$%d", len(args)),
+		args...,
+	)
+	if err != nil {
+		log.Printf("currency_repository.get_cursor_page query_error: %v", err)
+		return pagination.CursorPage[entity.Currency]{}, apperror.Internal("db get currency cursor page", err.Error())
+	}
+	defer rows.Close()
+
+	var currencies []entity.Currency
+	for rows.Next() {
+		currency, err := scanCurrency(rows)
+		if err != nil {
+			log.Printf("currency_repository.get_cursor_page scan_error: %v", err)
+			return pagination.CursorPage[entity.Currency]{}, apperror.Internal("db scan currency", err.Error())
+		}
+		currencies = append(currencies, currency)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("currency_repository.get_cursor_page iterate_error: %v", err)
+		return pagination.CursorPage[entity.Currency]{}, apperror.Internal("db iterate currency cursor page", err.Error())
+	}
+
+	result := finishKeysetPage(keys, currencies, page.PageSize, func(currency entity.Currency) pagination.Cursor {
+		cursor := pagination.Cursor{ID: currency.ID}
+		switch column {
+		case "code":
+			cursor.Key = currency.Code
+		case "full_name":
+			cursor.Key = currency.FullName
+		}
+		return cursor
+	})
+
+	log.Printf("currency_repository.get_cursor_page ok count=%d", len(result.Items))
+	return result, nil
+}
+
+// currencyFilterConditions renders filter as SQL conditions with positional
+// arguments.
+func currencyFilterConditions(filter repository.CurrencyFilter) ([]string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if filter.Search != "" {
+		pattern := escapeLike(filter.Search)
+		args = append(args, pattern+"%", "%"+pattern+"%")
+		conditions = append(conditions, fmt.Sprintf(
+			"(code ILIKE $%d OR full_name ILIKE $%d)",
+			len(args)-1,
+			len(args),
+		))
+	}
+	if len(filter.Codes) > 0 {
+		args = append(args, pq.Array(filter.Codes))
+		conditions = append(conditions, fmt.Sprintf("code = ANY($%d)", len(args)))
+	}
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		conditions = append(conditions, fmt.Sprintf("active = $%d", len(args)))
+	}
+	if filter.Disabled != nil {
+		args = append(args, *filter.Disabled)
+		conditions = append(conditions, fmt.Sprintf("disabled = $%d", len(args)))
+	}
+	return conditions, args
+}
+
+// whereClause joins conditions into a WHERE clause, or returns an empty
+// string when there is nothing to filter.
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// currencyOrderBy maps the requested sort onto columns. The id is always the
+// last key so pages are stable when names repeat.
+func currencyOrderBy(page pagination.PageRequest) (string, error) {
+	column, err := currencySortColumn(page.SortBy)
+	if err != nil {
+		return "", err
+	}
+	direction := "ASC"
+	if page.Direction == pagination.SortDesc {
+		direction = "DESC"
+	}
+	if column == "id" {
+		return "id " + direction, nil
+	}
+	return column + " " + direction + ", id " + direction, nil
+}
+
+func currencySortColumn(sortBy string) (string, error) {
+	switch sortBy {
+	case "", repository.CurrencySortID:
+		return "id", nil
+	case repository.CurrencySortCode:
+		return "code", nil
+	case repository.CurrencySortName:
+		return "full_name", nil
+	default:
+		return "", apperror.Validation(
+			"invalid sort field",
+			"sort must be "+repository.CurrencySortID+", "+repository.CurrencySortCode+" or "+repository.CurrencySortName,
+		)
+	}
+}
+
+// escapeLike escapes the LIKE wildcards in a user supplied search term.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
 type rowScanner interface {
 	Scan(dest ...any) error
 }
 
-func scanCurrency(scanner rowScanner) (entity.Currency, error) {
-	var currency entity.Currency
-	if err := scanner.Scan(
+// currencyColumns lists the currency columns in the order currencyFields
+// expects them, optionally qualified with a table alias.
+func currencyColumns(alias string) string {
+	columns := []string{"id", "code", "full_name", "sign", "minor_units", "numeric_code", "countries", "active", "disabled"}
+	if alias != "" {
+		for i, column := range columns {
+			columns[i] = alias + "." + column
+		}
+	}
+	return strings.Join(columns, ", ")
+}
+
+func currencyFields(currency *entity.Currency) []any {
+	return []any{
 		&currency.ID,
 		&currency.Code,
 		&currency.FullName,
 		&currency.Sign,
-	); err != nil {
+		&currency.MinorUnits,
+		&currency.NumericCode,
+		pq.Array(&currency.Countries),
+		&currency.Active,
+		&currency.Disabled,
+	}
+}
+
+func scanCurrency(scanner rowScanner) (entity.Currency, error) {
+	var currency entity.Currency
+	if err := scanner.Scan(currencyFields(&currency)...); err != nil {
 		return entity.Currency{}, err
 	}
 
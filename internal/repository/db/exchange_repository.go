@@ -3,15 +3,17 @@ package db
 import (
 	"context"
 	apperror "currency-exchange/internal/error"
+	"currency-exchange/internal/pagination"
 	"currency-exchange/internal/repository"
 	"database/sql"
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"currency-exchange/internal/entity"
 
-	"github.com/shopspring/decimal"
+	"github.com/lib/pq"
 )
 
 type ExchangeRepositoryDB struct {
@@ -26,56 +28,234 @@ func NewExchangeRepository(db *sql.DB) *ExchangeRepositoryDB {
 
 func (r *ExchangeRepositoryDB) Create(ctx context.Context, rate entity.ExchangeRate) (int64, error) {
 	log.Printf("exchange_repository.create start base_id=%d target_id=%d", rate.BaseCurrency.ID, rate.TargetCurrency.ID)
-	row := r.db.QueryRowContext(
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("exchange_repository.create begin_error: %v", err)
+		return 0, apperror.Internal("db begin create exchange rate", err.Error())
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(
 		ctx,
-		`INSERT INTO exchange_rates (base_currency_id, target_currency_id, rate)
-		 VALUES ($1, $2, $3)
+		`INSERT INTO exchange_rates (base_currency_id, target_currency_id, rate, effective_from, sources, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
 		 RETURNING id`,
 		rate.BaseCurrency.ID,
 		rate.TargetCurrency.ID,
 		rate.Rate,
+		rate.EffectiveFrom,
+		pq.Array(nonNilSources(rate.Sources)),
+		rate.UpdatedAt,
 	)
 
 	var id int64
 	if err := row.Scan(&id); err != nil {
+		if isUniqueViolation(err) {
+			log.Printf("exchange_repository.create conflict base_id=%d target_id=%d", rate.BaseCurrency.ID, rate.TargetCurrency.ID)
+			return 0, apperror.Conflict("exchange rate already exists", rate.BaseCurrency.Code+"/"+rate.TargetCurrency.Code)
+		}
 		log.Printf("exchange_repository.create error: %v", err)
 		return 0, apperror.Internal("db create exchange rate", err.Error())
 	}
+	rate.ID = id
+
+	if err := insertRateVersion(ctx, tx, rate); err != nil {
+		log.Printf("exchange_repository.create history_error: %v", err)
+		return 0, apperror.Internal("db create exchange rate history", err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("exchange_repository.create commit_error: %v", err)
+		return 0, apperror.Internal("db commit create exchange rate", err.Error())
+	}
 
 	log.Printf("exchange_repository.create ok id=%d", id)
 	return id, nil
 }
 
+// Update replaces a rate and records the new version. When the rate moves to
+// another currency pair, the history of the old pair is closed with a
+// deletion version in the same transaction, so the rate book rebuilt for a
+// later moment no longer contains the old pair.
 func (r *ExchangeRepositoryDB) Update(ctx context.Context, rate entity.ExchangeRate) error {
 	log.Printf("exchange_repository.update start id=%d", rate.ID)
-	result, err := r.db.ExecContext(
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("exchange_repository.update begin_error: %v", err)
+		return apperror.Internal("db begin update exchange rate", err.Error())
+	}
+	defer tx.Rollback()
+
+	var baseID, targetID int64
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT base_currency_id, target_currency_id FROM exchange_rates WHERE id = $1 FOR UPDATE`,
+		rate.ID,
+	).Scan(&baseID, &targetID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Printf("exchange_repository.update not_found id=%d", rate.ID)
+			return apperror.NotFound("exchange rate not found", "id="+fmt.Sprint(rate.ID))
+		}
+		log.Printf("exchange_repository.update lock_error: %v", err)
+		return apperror.Internal("db lock exchange rate", err.Error())
+	}
+	if baseID != rate.BaseCurrency.ID || targetID != rate.TargetCurrency.ID {
+		log.Printf("exchange_repository.update pair_change id=%d base_id=%d target_id=%d", rate.ID, baseID, targetID)
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO exchange_rate_history (exchange_rate_id, base_currency_id, target_currency_id, rate, effective_from, deleted, changed_by, sources)
+			 SELECT id, base_currency_id, target_currency_id, rate, $2, TRUE, $3, sources
+			 FROM exchange_rates
+			 WHERE id = $1`,
+			rate.ID,
+			rate.EffectiveFrom,
+			rate.ChangedBy,
+		); err != nil {
+			log.Printf("exchange_repository.update close_history_error: %v", err)
+			return apperror.Internal("db close exchange rate history", err.Error())
+		}
+	}
+
+	if _, err := tx.ExecContext(
 		ctx,
 		`UPDATE exchange_rates
 		 SET base_currency_id = $1,
 		     target_currency_id = $2,
-		     rate = $3
-		 WHERE id = $4`,
+		     rate = $3,
+		     effective_from = $4,
+		     sources = $5,
+		     updated_at = $6
+		 WHERE id = $7`,
 		rate.BaseCurrency.ID,
 		rate.TargetCurrency.ID,
 		rate.Rate,
+		rate.EffectiveFrom,
+		pq.Array(nonNilSources(rate.Sources)),
+		rate.UpdatedAt,
 		rate.ID,
-	)
-	if err != nil {
+	); err != nil {
+		if isUniqueViolation(err) {
+			log.Printf("exchange_repository.update conflict id=%d", rate.ID)
+			return apperror.Conflict("exchange rate already exists", rate.BaseCurrency.Code+"/"+rate.TargetCurrency.Code)
+		}
 		log.Printf("exchange_repository.update error: %v", err)
 		return apperror.Internal("db update exchange rate", err.Error())
 	}
 
+	if err := insertRateVersion(ctx, tx, rate); err != nil {
+		log.Printf("exchange_repository.update history_error: %v", err)
+		return apperror.Internal("db update exchange rate history", err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("exchange_repository.update commit_error: %v", err)
+		return apperror.Internal("db commit update exchange rate", err.Error())
+	}
+
+	log.Printf("exchange_repository.update ok id=%d", rate.ID)
+	return nil
+}
+
+// Upsert writes the rate of a currency pair in one statement, so concurrent
+// feeds cannot create the pair twice, and records the new version.
+func (r *ExchangeRepositoryDB) Upsert(ctx context.Context, rate entity.ExchangeRate) (int64, bool, error) {
+	log.Printf("exchange_repository.upsert start base_id=%d target_id=%d", rate.BaseCurrency.ID, rate.TargetCurrency.ID)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("exchange_repository.upsert begin_error: %v", err)
+		return 0, false, apperror.Internal("db begin upsert exchange rate", err.Error())
+	}
+	defer tx.Rollback()
+
+	id, created, err := upsertRate(ctx, tx, rate)
+	if err != nil {
+		log.Printf("exchange_repository.upsert error: %v", err)
+		return 0, false, apperror.Internal("db upsert exchange rate", err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("exchange_repository.upsert commit_error: %v", err)
+		return 0, false, apperror.Internal("db commit upsert exchange rate", err.Error())
+	}
+
+	log.Printf("exchange_repository.upsert ok id=%d created=%t", id, created)
+	return id, created, nil
+}
+
+func (r *ExchangeRepositoryDB) UpsertAll(ctx context.Context, rates []entity.ExchangeRate) error {
+	return r.Refresh(ctx, rates, nil, time.Time{})
+}
+
+// Refresh marks the confirmed rates as updated at the given moment without
+// writing a new version, since only their freshness changed, in the same
+// transaction as the upserts.
+func (r *ExchangeRepositoryDB) Refresh(ctx context.Context, rates []entity.ExchangeRate, confirmed []int64, at time.Time) error {
+	log.Printf("exchange_repository.refresh start count=%d confirmed=%d", len(rates), len(confirmed))
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("exchange_repository.refresh begin_error: %v", err)
+		return apperror.Internal("db begin upsert exchange rates", err.Error())
+	}
+	defer tx.Rollback()
+
+	for _, rate := range rates {
+		if _, _, err := upsertRate(ctx, tx, rate); err != nil {
+			log.Printf("exchange_repository.refresh error base_id=%d target_id=%d: %v", rate.BaseCurrency.ID, rate.TargetCurrency.ID, err)
+			return apperror.Internal("db upsert exchange rates", err.Error())
+		}
+	}
+	if len(confirmed) > 0 {
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE exchange_rates SET updated_at = $2 WHERE id = ANY($1)`,
+			pq.Array(confirmed),
+			at,
+		); err != nil {
+			log.Printf("exchange_repository.refresh touch_error: %v", err)
+			return apperror.Internal("db touch exchange rates", err.Error())
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("exchange_repository.refresh commit_error: %v", err)
+		return apperror.Internal("db commit upsert exchange rates", err.Error())
+	}
+
+	log.Printf("exchange_repository.refresh ok count=%d confirmed=%d", len(rates), len(confirmed))
+	return nil
+}
+
+// Delete removes a rate and, in the same statement, appends a deletion
+// version to its history so the audit trail shows who deleted it and when.
+func (r *ExchangeRepositoryDB) Delete(ctx context.Context, id int64, actor string, at time.Time) error {
+	log.Printf("exchange_repository.delete start id=%d actor=%s", id, actor)
+	result, err := r.db.ExecContext(
+		ctx,
+		`WITH deleted_rate AS (
+		     DELETE FROM exchange_rates
+		     WHERE id = $1
+		     RETURNING id, base_currency_id, target_currency_id, rate, sources
+		 )
+		 INSERT INTO exchange_rate_history (exchange_rate_id, base_currency_id, target_currency_id, rate, effective_from, deleted, changed_by, sources)
+		 SELECT id, base_currency_id, target_currency_id, rate, $2, TRUE, $3, sources
+		 FROM deleted_rate`,
+		id,
+		at,
+		actor,
+	)
+	if err != nil {
+		log.Printf("exchange_repository.delete error: %v", err)
+		return apperror.Internal("db delete exchange rate", err.Error())
+	}
+
 	affected, err := result.RowsAffected()
 	if err != nil {
-		log.Printf("exchange_repository.update rows_affected_error: %v", err)
-		return apperror.Internal("db check exchange rate update", err.Error())
+		log.Printf("exchange_repository.delete rows_affected_error: %v", err)
+		return apperror.Internal("db check exchange rate delete", err.Error())
 	}
 	if affected == 0 {
-		log.Printf("exchange_repository.update not_found id=%d", rate.ID)
-		return apperror.NotFound("exchange rate not found", "id="+fmt.Sprint(rate.ID))
+		log.Printf("exchange_repository.delete not_found id=%d", id)
+		return apperror.NotFound("exchange rate not found", "id="+fmt.Sprint(id))
 	}
 
-	log.Printf("exchange_repository.update ok id=%d", rate.ID)
+	log.Printf("exchange_repository.delete ok id=%d", id)
 	return nil
 }
 
@@ -85,8 +265,11 @@ func (r *ExchangeRepositoryDB) GetByID(ctx context.Context, id int64) (entity.Ex
 		ctx,
 		`SELECT er.id,
 		        er.rate,
-		        bc.id, bc.code, bc.full_name, bc.sign,
-		        tc.id, tc.code, tc.full_name, tc.sign
+		        er.effective_from,
+		        er.sources,
+		        er.updated_at,
+		        `+currencyColumns("bc")+`,
+		        `+currencyColumns("tc")+`
 		 FROM exchange_rates er
 		 JOIN currencies bc ON bc.id = er.base_currency_id
 		 JOIN currencies tc ON tc.id = er.target_currency_id
@@ -108,72 +291,414 @@ func (r *ExchangeRepositoryDB) GetByID(ctx context.Context, id int64) (entity.Ex
 	return rate, nil
 }
 
-func (r *ExchangeRepositoryDB) GetRate(ctx context.Context, baseId int64, targetId int64) (decimal.Decimal, error) {
-	log.Printf("exchange_repository.get_rate start base_id=%d target_id=%d", baseId, targetId)
-	var rate decimal.Decimal
-	if err := r.db.QueryRowContext(
+func (r *ExchangeRepositoryDB) GetByPair(ctx context.Context, baseID int64, targetID int64) (entity.ExchangeRate, error) {
+	log.Printf("exchange_repository.get_by_pair start base_id=%d target_id=%d", baseID, targetID)
+	row := r.db.QueryRowContext(
 		ctx,
-		`WITH normalized_rates AS (
-			SELECT base_currency_id AS from_id,
-			       target_currency_id AS to_id,
-			       rate::numeric AS rate
-			FROM exchange_rates
-			UNION ALL
-			SELECT target_currency_id AS from_id,
-			       base_currency_id AS to_id,
-			       1 / rate::numeric AS rate
-			FROM exchange_rates
-		),
-		direct_rate AS (
-			SELECT rate, 0 AS priority
-			FROM normalized_rates
-			WHERE from_id = $1 AND to_id = $2
-		),
-		one_hop_rate AS (
-			SELECT r1.rate * r2.rate AS rate, 1 AS priority
-			FROM normalized_rates r1
-			JOIN normalized_rates r2 ON r1.to_id = r2.from_id
-			WHERE r1.from_id = $1 AND r2.to_id = $2
-		)
-		SELECT rate
-		FROM (
-			SELECT * FROM direct_rate
-			UNION ALL
-			SELECT * FROM one_hop_rate
-		) candidate_rates
-		ORDER BY priority
-		LIMIT 1`,
-		baseId,
-		targetId,
-	).Scan(&rate); err != nil {
+		`SELECT er.id,
+		        er.rate,
+		        er.effective_from,
+		        er.sources,
+		        er.updated_at,
+		        `+currencyColumns("bc")+`,
+		        `+currencyColumns("tc")+`
+		 FROM exchange_rates er
+		 JOIN currencies bc ON bc.id = er.base_currency_id
+		 JOIN currencies tc ON tc.id = er.target_currency_id
+		 WHERE er.base_currency_id = $1 AND er.target_currency_id = $2`,
+		baseID,
+		targetID,
+	)
+
+	rate, err := scanExchangeRates(row)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			log.Printf("exchange_repository.get_rate not_found base_id=%d target_id=%d", baseId, targetId)
-			return decimal.Decimal{}, apperror.NotFound("exchange rate not found", "base_id="+fmt.Sprint(baseId)+" target_id="+fmt.Sprint(targetId))
+			log.Printf("exchange_repository.get_by_pair not_found base_id=%d target_id=%d", baseID, targetID)
+			return entity.ExchangeRate{}, apperror.NotFound(
+				"exchange rate not found",
+				fmt.Sprintf("base_id=%d target_id=%d", baseID, targetID),
+			)
 		}
-		log.Printf("exchange_repository.get_rate error: %v", err)
-		return decimal.Decimal{}, apperror.Internal("db get exchange rate", err.Error())
+		log.Printf("exchange_repository.get_by_pair error: %v", err)
+		return entity.ExchangeRate{}, apperror.Internal("db get exchange rate by pair", err.Error())
 	}
 
-	log.Printf("exchange_repository.get_rate ok rate=%s", rate.String())
+	log.Printf("exchange_repository.get_by_pair ok id=%d", rate.ID)
 	return rate, nil
 }
 
+func (r *ExchangeRepositoryDB) GetHistory(ctx context.Context, id int64) ([]entity.ExchangeRateVersion, error) {
+	log.Printf("exchange_repository.get_history start id=%d", id)
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT h.id,
+		        h.exchange_rate_id,
+		        h.rate,
+		        h.effective_from,
+		        h.recorded_at,
+		        h.deleted,
+		        h.changed_by,
+		        h.sources,
+		        h.override_reason,
+		        `+currencyColumns("bc")+`,
+		        `+currencyColumns("tc")+`
+		 FROM exchange_rate_history h
+		 JOIN currencies bc ON bc.id = h.base_currency_id
+		 JOIN currencies tc ON tc.id = h.target_currency_id
+		 WHERE h.exchange_rate_id = $1
+		 ORDER BY h.effective_from DESC, h.recorded_at DESC, h.id DESC`,
+		id,
+	)
+	if err != nil {
+		log.Printf("exchange_repository.get_history query_error: %v", err)
+		return nil, apperror.Internal("db get exchange rate history", err.Error())
+	}
+	defer rows.Close()
+
+	var versions []entity.ExchangeRateVersion
+	for rows.Next() {
+		var version entity.ExchangeRateVersion
+		fields := []any{
+			&version.ID,
+			&version.ExchangeRateID,
+			&version.Rate,
+			&version.EffectiveFrom,
+			&version.RecordedAt,
+			&version.Deleted,
+			&version.ChangedBy,
+			pq.Array(&version.Sources),
+			&version.OverrideReason,
+		}
+		fields = append(fields, currencyFields(&version.BaseCurrency)...)
+		fields = append(fields, currencyFields(&version.TargetCurrency)...)
+		if err := rows.Scan(fields...); err != nil {
+			log.Printf("exchange_repository.get_history scan_error: %v", err)
+			return nil, apperror.Internal("db scan exchange rate version", err.Error())
+		}
+		versions = append(versions, version)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("exchange_repository.get_history iterate_error: %v", err)
+		return nil, apperror.Internal("db iterate exchange rate history", err.Error())
+	}
+
+	log.Printf("exchange_repository.get_history ok id=%d count=%d", id, len(versions))
+	return versions, nil
+}
+
+func (r *ExchangeRepositoryDB) GetAll(ctx context.Context) ([]entity.ExchangeRate, error) {
+	log.Printf("exchange_repository.get_all start")
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT er.id,
+		        er.rate,
+		        er.effective_from,
+		        er.sources,
+		        er.updated_at,
+		        `+currencyColumns("bc")+`,
+		        `+currencyColumns("tc")+`
+		 FROM exchange_rates er
+		 JOIN currencies bc ON bc.id = er.base_currency_id
+		 JOIN currencies tc ON tc.id = er.target_currency_id
+		 ORDER BY er.id`,
+	)
+	if err != nil {
+		log.Printf("exchange_repository.get_all query_error: %v", err)
+		return nil, apperror.Internal("db get all exchange rates", err.Error())
+	}
+	defer rows.Close()
+
+	rates, err := scanExchangeRateRows(rows)
+	if err != nil {
+		log.Printf("exchange_repository.get_all scan_error: %v", err)
+		return nil, apperror.Internal("db scan exchange rates", err.Error())
+	}
+
+	log.Printf("exchange_repository.get_all ok count=%d", len(rates))
+	return rates, nil
+}
+
+// GetAllAt rebuilds the rate book as it was at the given moment: for every
+// pair it takes the latest history version effective at that time, leaving
+// out pairs whose latest version is a deletion. The update time of a rebuilt
+// rate is when its version was recorded.
+func (r *ExchangeRepositoryDB) GetAllAt(ctx context.Context, at time.Time) ([]entity.ExchangeRate, error) {
+	log.Printf("exchange_repository.get_all_at start at=%s", at.Format(time.RFC3339))
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT COALESCE(h.exchange_rate_id, 0),
+		        h.rate,
+		        h.effective_from,
+		        h.sources,
+		        h.recorded_at,
+		        `+currencyColumns("bc")+`,
+		        `+currencyColumns("tc")+`
+		 FROM (
+		     SELECT DISTINCT ON (base_currency_id, target_currency_id) *
+		     FROM exchange_rate_history
+		     WHERE effective_from <= $1
+		     ORDER BY base_currency_id, target_currency_id, effective_from DESC, recorded_at DESC, id DESC
+		 ) h
+		 JOIN currencies bc ON bc.id = h.base_currency_id
+		 JOIN currencies tc ON tc.id = h.target_currency_id
+		 WHERE NOT h.deleted
+		 ORDER BY h.exchange_rate_id`,
+		at,
+	)
+	if err != nil {
+		log.Printf("exchange_repository.get_all_at query_error: %v", err)
+		return nil, apperror.Internal("db get exchange rates at", err.Error())
+	}
+	defer rows.Close()
+
+	rates, err := scanExchangeRateRows(rows)
+	if err != nil {
+		log.Printf("exchange_repository.get_all_at scan_error: %v", err)
+		return nil, apperror.Internal("db scan exchange rates", err.Error())
+	}
+
+	log.Printf("exchange_repository.get_all_at ok count=%d", len(rates))
+	return rates, nil
+}
+
+// GetPage lists rates with both currencies embedded, ordered by id. The sort
+// is validated by the service.
+func (r *ExchangeRepositoryDB) GetPage(ctx context.Context, filter repository.RateFilter, page pagination.PageRequest) (pagination.Page[entity.ExchangeRate], error) {
+	log.Printf("exchange_repository.get_page start page=%d size=%d", page.PageNumber, page.PageSize)
+	if page.PageNumber < 1 || page.PageSize < 1 {
+		log.Printf("exchange_repository.get_page validation_error page=%d size=%d", page.PageNumber, page.PageSize)
+		return pagination.Page[entity.ExchangeRate]{}, apperror.Validation("invalid page params", fmt.Sprintf(
+			"invalid page params: pageNumber=%d pageSize=%d",
+			page.PageNumber,
+			page.PageSize,
+		))
+	}
+	direction := "ASC"
+	if page.Direction == pagination.SortDesc {
+		direction = "DESC"
+	}
+	conditions, args := rateFilterConditions(filter)
+	joins := `
+		 FROM exchange_rates er
+		 JOIN currencies bc ON bc.id = er.base_currency_id
+		 JOIN currencies tc ON tc.id = er.target_currency_id` + whereClause(conditions)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*)`+joins, args...).Scan(&total); err != nil {
+		log.Printf("exchange_repository.get_page count_error: %v", err)
+		return pagination.Page[entity.ExchangeRate]{}, apperror.Internal("db count exchange rates", err.Error())
+	}
+
+	limit := int64(page.PageSize)
+	offset := int64(page.PageNumber-1) * int64(page.PageSize)
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT er.id,
+		        er.rate,
+		        er.effective_from,
+		        er.sources,
+		        er.updated_at,
+		        `+currencyColumns("bc")+`,
+		        `+currencyColumns("tc")+joins+`
+		 ORDER BY er.id `+direction+`
+		 LIMIT `+fmt.Sprintf("$%d OFFSET $%d", len(args)+1, len(args)+2),
+		append(args, limit, offset)...,
+	)
+	if err != nil {
+		log.Printf("exchange_repository.get_page query_error: %v", err)
+		return pagination.Page[entity.ExchangeRate]{}, apperror.Internal("db get exchange rate page", err.Error())
+	}
+	defer rows.Close()
+
+	rates, err := scanExchangeRateRows(rows)
+	if err != nil {
+		log.Printf("exchange_repository.get_page scan_error: %v", err)
+		return pagination.Page[entity.ExchangeRate]{}, apperror.Internal("db scan exchange rates", err.Error())
+	}
+
+	log.Printf("exchange_repository.get_page ok total=%d", total)
+	return pagination.Page[entity.ExchangeRate]{
+		Items:      rates,
+		PageNumber: page.PageNumber,
+		PageSize:   page.PageSize,
+		Total:      total,
+	}, nil
+}
+
+// GetCursorPage lists rates after (or before) the requested keyset position.
+func (r *ExchangeRepositoryDB) GetCursorPage(ctx context.Context, filter repository.RateFilter, page pagination.CursorRequest) (pagination.CursorPage[entity.ExchangeRate], error) {
+	log.Printf("exchange_repository.get_cursor_page start size=%d has_cursor=%t", page.PageSize, page.Position != nil)
+	if page.PageSize < 1 {
+		log.Printf("exchange_repository.get_cursor_page validation_error size=%d", page.PageSize)
+		return pagination.CursorPage[entity.ExchangeRate]{}, apperror.Validation("invalid page params", fmt.Sprintf(
+			"invalid page params: pageSize=%d",
+			page.PageSize,
+		))
+	}
+	keys := newKeyset(page)
+	conditions, args := rateFilterConditions(filter)
+	conditions, args, orderBy := keys.apply(conditions, args, "", "er.id")
+	args = append(args, int64(page.PageSize)+1)
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT er.id,
+		        er.rate,
+		        er.effective_from,
+		        er.sources,
+		        er.updated_at,
+		        `+currencyColumns("bc")+`,
+		        `+currencyColumns("tc")+`
+		 FROM exchange_rates er
+		 JOIN currencies bc ON bc.id = er.base_currency_id
+		 JOIN currencies tc ON tc.id = er.target_currency_id`+whereClause(conditions)+`
+		 ORDER BY `+orderBy+`
+		 LIMIT `+fmt.Sprintf("$%d", len(args)),
+		args...,
+	)
+	if err != nil {
+		log.Printf("exchange_repository.get_cursor_page query_error: %v", err)
+		return pagination.CursorPage[entity.ExchangeRate]{}, apperror.Internal("db get exchange rate cursor page", err.Error())
+	}
+	defer rows.Close()
+
+	rates, err := scanExchangeRateRows(rows)
+	if err != nil {
+		log.Printf("exchange_repository.get_cursor_page scan_error: %v", err)
+		return pagination.CursorPage[entity.ExchangeRate]{}, apperror.Internal("db scan exchange rates", err.Error())
+	}
+
+	result := finishKeysetPage(keys, rates, page.PageSize, func(rate entity.ExchangeRate) pagination.Cursor {
+		return pagination.Cursor{ID: rate.ID}
+	})
+	log.Printf("exchange_repository.get_cursor_page ok count=%d", len(result.Items))
+	return result, nil
+}
+
+// rateFilterConditions renders filter as SQL conditions over the er, bc and
+// tc aliases of the joined rate query.
+func rateFilterConditions(filter repository.RateFilter) ([]string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if filter.BaseCode != "" {
+		args = append(args, filter.BaseCode)
+		conditions = append(conditions, fmt.Sprintf("bc.code = $%d", len(args)))
+	}
+	if filter.TargetCode != "" {
+		args = append(args, filter.TargetCode)
+		conditions = append(conditions, fmt.Sprintf("tc.code = $%d", len(args)))
+	}
+	if filter.CurrencyCode != "" {
+		args = append(args, filter.CurrencyCode)
+		conditions = append(conditions, fmt.Sprintf("(bc.code = $%d OR tc.code = $%d)", len(args), len(args)))
+	}
+	if filter.UpdatedSince != nil {
+		args = append(args, *filter.UpdatedSince)
+		conditions = append(conditions, fmt.Sprintf("er.updated_at >= $%d", len(args)))
+	}
+	return conditions, args
+}
+
+// upsertRate writes the rate of a pair and its history version inside tx and
+// reports whether the pair was created.
+func upsertRate(ctx context.Context, tx *sql.Tx, rate entity.ExchangeRate) (int64, bool, error) {
+	var (
+		id      int64
+		created bool
+	)
+	err := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO exchange_rates (base_currency_id, target_currency_id, rate, effective_from, sources, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (base_currency_id, target_currency_id) DO UPDATE
+		 SET rate = EXCLUDED.rate,
+		     effective_from = EXCLUDED.effective_from,
+		     sources = EXCLUDED.sources,
+		     updated_at = EXCLUDED.updated_at
+		 RETURNING id, xmax = 0`,
+		rate.BaseCurrency.ID,
+		rate.TargetCurrency.ID,
+		rate.Rate,
+		rate.EffectiveFrom,
+		pq.Array(nonNilSources(rate.Sources)),
+		rate.UpdatedAt,
+	).Scan(&id, &created)
+	if err != nil {
+		return 0, false, err
+	}
+	rate.ID = id
+	if err := insertRateVersion(ctx, tx, rate); err != nil {
+		return 0, false, err
+	}
+	return id, created, nil
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func insertRateVersion(ctx context.Context, tx *sql.Tx, rate entity.ExchangeRate) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO exchange_rate_history (exchange_rate_id, base_currency_id, target_currency_id, rate, effective_from, sources, changed_by, override_reason)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		rate.ID,
+		rate.BaseCurrency.ID,
+		rate.TargetCurrency.ID,
+		rate.Rate,
+		rate.EffectiveFrom,
+		pq.Array(nonNilSources(rate.Sources)),
+		rate.ChangedBy,
+		rate.OverrideReason,
+	)
+	return err
+}
+
+// nonNilSources keeps an empty source list from being written as NULL.
+func nonNilSources(sources []string) []string {
+	if sources == nil {
+		return []string{}
+	}
+	return sources
+}
+
+func scanExchangeRateRows(rows *sql.Rows) ([]entity.ExchangeRate, error) {
+	var rates []entity.ExchangeRate
+	for rows.Next() {
+		rate, err := scanExchangeRates(rows)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
+
 func scanExchangeRates(scanner rowScanner) (entity.ExchangeRate, error) {
 	var rate entity.ExchangeRate
-	if err := scanner.Scan(
-		&rate.ID,
-		&rate.Rate,
-		&rate.BaseCurrency.ID,
-		&rate.BaseCurrency.Code,
-		&rate.BaseCurrency.FullName,
-		&rate.BaseCurrency.Sign,
-		&rate.TargetCurrency.ID,
-		&rate.TargetCurrency.Code,
-		&rate.TargetCurrency.FullName,
-		&rate.TargetCurrency.Sign,
-	); err != nil {
+	if err := scanner.Scan(exchangeRateFields(&rate)...); err != nil {
 		return entity.ExchangeRate{}, err
 	}
 
 	return rate, nil
 }
+
+// exchangeRateFields lists the scan targets of a joined rate row: id, rate,
+// effective_from, sources, updated_at, then the base and target currency
+// columns.
+func exchangeRateFields(rate *entity.ExchangeRate) []any {
+	fields := []any{
+		&rate.ID,
+		&rate.Rate,
+		&rate.EffectiveFrom,
+		pq.Array(&rate.Sources),
+		&rate.UpdatedAt,
+	}
+	fields = append(fields, currencyFields(&rate.BaseCurrency)...)
+	fields = append(fields, currencyFields(&rate.TargetCurrency)...)
+	return fields
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+
+	"currency-exchange/internal/pagination"
+)
+
+// keyset holds the effective position and order of a keyset page query. A
+// cursor fixes the order it was issued for, so a listing cannot change sort
+// halfway through.
+type keyset struct {
+	position  *pagination.Cursor
+	sortBy    string
+	direction pagination.SortDirection
+	backward  bool
+}
+
+func newKeyset(page pagination.CursorRequest) keyset {
+	if page.Position != nil {
+		return keyset{
+			position:  page.Position,
+			sortBy:    page.Position.SortBy,
+			direction: page.Position.Direction,
+			backward:  page.Position.Backward,
+		}
+	}
+	return keyset{sortBy: page.SortBy, direction: page.Direction}
+}
+
+// apply appends the comparison with the cursor to conditions and returns the
+// ORDER BY clause. keyColumn is the sort column, or empty when sorting by
+// idColumn alone. Walking backwards flips the order; finishKeysetPage puts
+// the rows back into display order.
+func (k keyset) apply(conditions []string, args []any, keyColumn string, idColumn string) ([]string, []any, string) {
+	comparison, order := ">", "ASC"
+	if (k.direction == pagination.SortDesc) != k.backward {
+		comparison, order = "<", "DESC"
+	}
+	if k.position != nil {
+		if keyColumn == "" {
+			args = append(args, k.position.ID)
+			conditions = append(conditions, fmt.Sprintf("%s %s $%d", idColumn, comparison, len(args)))
+		} else {
+			args = append(args, k.position.Key, k.position.ID)
+			conditions = append(conditions, fmt.Sprintf(
+				"(%s, %s) %s ($%d, $%d)",
+				keyColumn, idColumn, comparison, len(args)-1, len(args),
+			))
+		}
+	}
+	if keyColumn == "" {
+		return conditions, args, idColumn + " " + order
+	}
+	return conditions, args, keyColumn + " " + order + ", " + idColumn + " " + order
+}
+
+// finishKeysetPage turns the rows of a query limited to pageSize+1 into a
+// page: the extra row only tells whether the listing goes on in the
+// direction of travel. cursorOf returns the key and id of an item.
+func finishKeysetPage[T any](k keyset, items []T, pageSize int32, cursorOf func(T) pagination.Cursor) pagination.CursorPage[T] {
+	more := len(items) > int(pageSize)
+	if more {
+		items = items[:pageSize]
+	}
+	if k.backward {
+		slices.Reverse(items)
+	}
+
+	page := pagination.CursorPage[T]{Items: items, PageSize: pageSize}
+	if len(items) == 0 {
+		return page
+	}
+	encode := func(item T, backward bool) string {
+		cursor := cursorOf(item)
+		cursor.Backward = backward
+		cursor.SortBy = k.sortBy
+		cursor.Direction = k.direction
+		return pagination.EncodeCursor(cursor)
+	}
+	if k.backward || more {
+		page.Next = encode(items[len(items)-1], false)
+	}
+	if (k.backward && more) || (!k.backward && k.position != nil) {
+		page.Prev = encode(items[0], true)
+	}
+	return page
+}
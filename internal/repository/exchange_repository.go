@@ -3,13 +3,41 @@ package repository
 import (
 	"context"
 	"currency-exchange/internal/entity"
-
-	"github.com/shopspring/decimal"
+	"currency-exchange/internal/pagination"
+	"time"
 )
 
+const RateSortID = "id"
+
+// RateFilter narrows a rate listing. Zero values do not filter.
+type RateFilter struct {
+	// BaseCode and TargetCode match the respective side of the pair.
+	BaseCode   string
+	TargetCode string
+	// CurrencyCode matches rates with the currency on either side.
+	CurrencyCode string
+	// UpdatedSince keeps rates written or confirmed at or after the moment.
+	UpdatedSince *time.Time
+}
+
 type ExchangeRepository interface {
 	Create(ctx context.Context, rate entity.ExchangeRate) (int64, error)
 	Update(ctx context.Context, rate entity.ExchangeRate) error
+	// Upsert creates or updates the rate of the pair and reports whether the
+	// pair was created.
+	Upsert(ctx context.Context, rate entity.ExchangeRate) (id int64, created bool, err error)
+	// UpsertAll upserts every rate in one transaction: either all of them are
+	// written or none is.
+	UpsertAll(ctx context.Context, rates []entity.ExchangeRate) error
+	// Refresh upserts rates and sets the update time of the unchanged rates
+	// confirmed alongside them in one transaction.
+	Refresh(ctx context.Context, rates []entity.ExchangeRate, confirmed []int64, at time.Time) error
+	Delete(ctx context.Context, id int64, actor string, at time.Time) error
 	GetByID(ctx context.Context, id int64) (entity.ExchangeRate, error)
-	GetRate(ctx context.Context, baseId int64, targetId int64) (decimal.Decimal, error)
+	GetByPair(ctx context.Context, baseID int64, targetID int64) (entity.ExchangeRate, error)
+	GetHistory(ctx context.Context, id int64) ([]entity.ExchangeRateVersion, error)
+	GetAll(ctx context.Context) ([]entity.ExchangeRate, error)
+	GetAllAt(ctx context.Context, at time.Time) ([]entity.ExchangeRate, error)
+	GetPage(ctx context.Context, filter RateFilter, page pagination.PageRequest) (pagination.Page[entity.ExchangeRate], error)
+	GetCursorPage(ctx context.Context, filter RateFilter, page pagination.CursorRequest) (pagination.CursorPage[entity.ExchangeRate], error)
 }
@@ -0,0 +1,383 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"currency-exchange/internal/dto"
+	"currency-exchange/internal/entity"
+	apperror "currency-exchange/internal/error"
+	"currency-exchange/internal/iso4217"
+)
+
+const (
+	// ImportSkipExisting leaves currencies that already exist untouched.
+	ImportSkipExisting = "skip"
+	// ImportFailExisting rejects the import when a currency already exists.
+	ImportFailExisting = "fail"
+
+	// CurrencyImportMaxRows caps the number of rows in a single currency import.
+	CurrencyImportMaxRows = 1000
+)
+
+// currencyCSVColumns is the column order of a currency export. Imports
+// require a header naming their columns; code, full_name and sign are
+// mandatory and the others fall back to the same defaults as CreateCurrency.
+var currencyCSVColumns = []string{"code", "full_name", "sign", "minor_units", "numeric_code", "countries", "active", "disabled", "custom"}
+
+// currencyImportRow is one input row. err holds a problem found while
+// parsing the row so it can be reported next to the others.
+type currencyImportRow struct {
+	line    int
+	request dto.CreateCurrencyRequest
+	err     error
+}
+
+// ImportCurrencies validates every row of a CSV or JSON currency list with
+// the rules of CreateCurrency and, unless dryRun is set, creates them all in
+// one transaction. onExisting decides whether codes that already exist are
+// skipped or make the whole import fail. When any row is invalid nothing is
+// written. A code created concurrently, after the rows were validated, is
+// reported as skipped, or fails the import with a conflict.
+func (c *CurrencyService) ImportCurrencies(format string, input io.Reader, onExisting string, dryRun bool) (dto.CurrencyImportDto, error) {
+	log.Printf("currency_service.import_currencies start format=%s on_existing=%s dry_run=%t", format, onExisting, dryRun)
+	if onExisting != ImportSkipExisting && onExisting != ImportFailExisting {
+		log.Printf("currency_service.import_currencies validation_error on_existing=%s", onExisting)
+		return dto.CurrencyImportDto{}, apperror.Validation(
+			"invalid onExisting mode",
+			"onExisting must be "+ImportSkipExisting+" or "+ImportFailExisting,
+		)
+	}
+	rows, err := parseCurrencyImport(format, input)
+	if err != nil {
+		log.Printf("currency_service.import_currencies validation_error: %v", err)
+		return dto.CurrencyImportDto{}, err
+	}
+	if len(rows) == 0 {
+		log.Printf("currency_service.import_currencies validation_error: no rows")
+		return dto.CurrencyImportDto{}, apperror.Validation("import must contain at least one row", "no rows")
+	}
+	if len(rows) > CurrencyImportMaxRows {
+		log.Printf("currency_service.import_currencies validation_error: rows=%d", len(rows))
+		return dto.CurrencyImportDto{}, apperror.Validation(
+			"import is too large",
+			"import must contain no more than "+fmt.Sprint(CurrencyImportMaxRows)+" rows",
+		)
+	}
+
+	existing, err := c.currencyRepository.GetAll(c.ctx)
+	if err != nil {
+		log.Printf("currency_service.import_currencies currencies_error: %v", err)
+		return dto.CurrencyImportDto{}, apperror.Internal("get currencies", err.Error())
+	}
+	exists := make(map[string]bool, len(existing))
+	for _, currency := range existing {
+		exists[currency.Code] = true
+	}
+
+	result := dto.CurrencyImportDto{DryRun: dryRun, Rows: make([]dto.CurrencyImportRowDto, 0, len(rows))}
+	seen := make(map[string]int)
+	var creates []entity.Currency
+	for _, row := range rows {
+		code := normalizeCode(row.request.Code)
+		report := dto.CurrencyImportRowDto{Line: row.line, Code: code}
+		currency, err := validateCurrencyImportRow(row, code, seen)
+		switch {
+		case err != nil:
+		case exists[code] && onExisting == ImportSkipExisting:
+			report.Status = dto.CurrencyImportSkipped
+			result.Skipped++
+		case exists[code]:
+			err = apperror.Validation("currency already exists", "code="+code)
+		default:
+			report.Status = dto.CurrencyImportCreated
+			result.Created++
+			creates = append(creates, currency)
+		}
+		if err != nil {
+			report.Status = dto.CurrencyImportInvalid
+			report.Error = &dto.BatchErrorDto{Kind: apperror.KindOf(err), Message: apperror.MessageOf(err)}
+			result.Invalid++
+		}
+		if code != "" {
+			seen[code] = row.line
+		}
+		result.Rows = append(result.Rows, report)
+	}
+
+	if dryRun || result.Invalid > 0 || len(creates) == 0 {
+		log.Printf("currency_service.import_currencies ok applied=false created=%d skipped=%d invalid=%d", result.Created, result.Skipped, result.Invalid)
+		return result, nil
+	}
+	if onExisting == ImportFailExisting {
+		if err := c.currencyRepository.CreateAll(c.ctx, creates); err != nil {
+			if errors.Is(err, apperror.ErrConflict) {
+				log.Printf("currency_service.import_currencies conflict: %v", err)
+				return dto.CurrencyImportDto{}, err
+			}
+			log.Printf("currency_service.import_currencies error: %v", err)
+			return dto.CurrencyImportDto{}, apperror.Internal("import currencies", err.Error())
+		}
+	} else {
+		inserted, _, err := c.currencyRepository.Upsert(c.ctx, creates, false)
+		if err != nil {
+			log.Printf("currency_service.import_currencies error: %v", err)
+			return dto.CurrencyImportDto{}, apperror.Internal("import currencies", err.Error())
+		}
+		if len(inserted) < len(creates) {
+			markConcurrentlyCreated(&result, inserted)
+		}
+	}
+	result.Applied = true
+	log.Printf("currency_service.import_currencies ok applied=true created=%d skipped=%d", result.Created, result.Skipped)
+	return result, nil
+}
+
+// markConcurrentlyCreated reports the rows counted as created that the
+// upsert did not insert, because their code was created in the meantime, as
+// skipped.
+func markConcurrentlyCreated(result *dto.CurrencyImportDto, inserted []string) {
+	created := make(map[string]bool, len(inserted))
+	for _, code := range inserted {
+		created[code] = true
+	}
+	for i := range result.Rows {
+		row := &result.Rows[i]
+		if row.Status == dto.CurrencyImportCreated && !created[row.Code] {
+			row.Status = dto.CurrencyImportSkipped
+			result.Created--
+			result.Skipped++
+		}
+	}
+}
+
+func validateCurrencyImportRow(row currencyImportRow, code string, seen map[string]int) (entity.Currency, error) {
+	if row.err != nil {
+		return entity.Currency{}, row.err
+	}
+	if line, ok := seen[code]; ok {
+		return entity.Currency{}, apperror.Validation(
+			"duplicate currency code",
+			code+" already given on line "+fmt.Sprint(line),
+		)
+	}
+	return buildCurrency(code, row.request.Custom, currencyInput{
+		fullName:    row.request.FullName,
+		sign:        row.request.Sign,
+		minorUnits:  row.request.MinorUnits,
+		numericCode: row.request.NumericCode,
+		countries:   row.request.Countries,
+		active:      row.request.Active,
+		disabled:    row.request.Disabled,
+	})
+}
+
+// ExportCurrencies writes the whole currencies table, withdrawn and disabled
+// currencies included, in a format ImportCurrencies reads back. Codes outside ISO 4217
+// are marked custom.
+func (c *CurrencyService) ExportCurrencies(format string, output io.Writer) error {
+	log.Printf("currency_service.export_currencies start format=%s", format)
+	if format != ImportFormatCSV && format != ImportFormatJSON {
+		log.Printf("currency_service.export_currencies validation_error format=%s", format)
+		return apperror.Validation(
+			"unsupported export format",
+			"format must be "+ImportFormatCSV+" or "+ImportFormatJSON,
+		)
+	}
+	currencies, err := c.currencyRepository.GetAll(c.ctx)
+	if err != nil {
+		log.Printf("currency_service.export_currencies error: %v", err)
+		return apperror.Internal("get currencies", err.Error())
+	}
+
+	if format == ImportFormatJSON {
+		items := make([]dto.CreateCurrencyRequest, 0, len(currencies))
+		for _, currency := range currencies {
+			items = append(items, exportCurrency(currency))
+		}
+		err = json.NewEncoder(output).Encode(items)
+	} else {
+		err = writeCurrencyCSV(output, currencies)
+	}
+	if err != nil {
+		log.Printf("currency_service.export_currencies write_error: %v", err)
+		return apperror.Internal("write currency export", err.Error())
+	}
+	log.Printf("currency_service.export_currencies ok count=%d", len(currencies))
+	return nil
+}
+
+func exportCurrency(currency entity.Currency) dto.CreateCurrencyRequest {
+	minorUnits, active, disabled := currency.MinorUnits, currency.Active, currency.Disabled
+	_, known := iso4217.Lookup(currency.Code)
+	return dto.CreateCurrencyRequest{
+		Code:        currency.Code,
+		FullName:    currency.FullName,
+		Sign:        currency.Sign,
+		MinorUnits:  &minorUnits,
+		NumericCode: currency.NumericCode,
+		Countries:   currency.Countries,
+		Active:      &active,
+		Disabled:    &disabled,
+		Custom:      !known,
+	}
+}
+
+func writeCurrencyCSV(output io.Writer, currencies []entity.Currency) error {
+	writer := csv.NewWriter(output)
+	if err := writer.Write(currencyCSVColumns); err != nil {
+		return err
+	}
+	for _, currency := range currencies {
+		item := exportCurrency(currency)
+		if err := writer.Write([]string{
+			item.Code,
+			item.FullName,
+			item.Sign,
+			fmt.Sprint(*item.MinorUnits),
+			item.NumericCode,
+			strings.Join(item.Countries, " "),
+			strconv.FormatBool(*item.Active),
+			strconv.FormatBool(*item.Disabled),
+			strconv.FormatBool(item.Custom),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func parseCurrencyImport(format string, input io.Reader) ([]currencyImportRow, error) {
+	switch format {
+	case ImportFormatCSV:
+		return parseCurrencyImportCSV(input)
+	case ImportFormatJSON:
+		return parseCurrencyImportJSON(input)
+	default:
+		return nil, apperror.Validation(
+			"unsupported import format",
+			"format must be "+ImportFormatCSV+" or "+ImportFormatJSON,
+		)
+	}
+}
+
+// parseCurrencyImportCSV reads records under a header row naming a subset of
+// currencyCSVColumns in any order. Countries are separated by spaces.
+func parseCurrencyImportCSV(input io.Reader) ([]currencyImportRow, error) {
+	reader := csv.NewReader(input)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apperror.Validation("invalid csv", err.Error())
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !isCurrencyCSVColumn(name) {
+			return nil, apperror.Validation("invalid csv header", "unknown column "+name)
+		}
+		columns[name] = i
+	}
+	for _, name := range currencyCSVColumns[:3] {
+		if _, ok := columns[name]; !ok {
+			return nil, apperror.Validation("invalid csv header", "missing column "+name)
+		}
+	}
+
+	var rows []currencyImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, apperror.Validation("invalid csv", err.Error())
+		}
+		line, _ := reader.FieldPos(0)
+		request, err := currencyFromCSV(columns, record)
+		rows = append(rows, currencyImportRow{line: line, request: request, err: err})
+	}
+}
+
+func isCurrencyCSVColumn(name string) bool {
+	for _, column := range currencyCSVColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+func currencyFromCSV(columns map[string]int, record []string) (dto.CreateCurrencyRequest, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	request := dto.CreateCurrencyRequest{
+		Code:        field("code"),
+		FullName:    field("full_name"),
+		Sign:        field("sign"),
+		NumericCode: field("numeric_code"),
+		Countries:   strings.Fields(field("countries")),
+	}
+	if value := field("minor_units"); value != "" {
+		minorUnits, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return request, apperror.Validation("invalid currency minor units", err.Error())
+		}
+		units := int32(minorUnits)
+		request.MinorUnits = &units
+	}
+	if value := field("active"); value != "" {
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			return request, apperror.Validation("invalid active flag", err.Error())
+		}
+		request.Active = &active
+	}
+	if value := field("disabled"); value != "" {
+		disabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return request, apperror.Validation("invalid disabled flag", err.Error())
+		}
+		request.Disabled = &disabled
+	}
+	if value := field("custom"); value != "" {
+		custom, err := strconv.ParseBool(value)
+		if err != nil {
+			return request, apperror.Validation("invalid custom flag", err.Error())
+		}
+		request.Custom = custom
+	}
+	return request, nil
+}
+
+// parseCurrencyImportJSON reads an array of CreateCurrencyRequest objects.
+func parseCurrencyImportJSON(input io.Reader) ([]currencyImportRow, error) {
+	var items []dto.CreateCurrencyRequest
+	decoder := json.NewDecoder(input)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&items); err != nil {
+		return nil, apperror.Validation("invalid json", err.Error())
+	}
+
+	rows := make([]currencyImportRow, 0, len(items))
+	for i, item := range items {
+		rows = append(rows, currencyImportRow{line: i + 1, request: item})
+	}
+	return rows, nil
+}
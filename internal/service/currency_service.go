@@ -5,14 +5,24 @@ import (
 	"currency-exchange/internal/dto"
 	"currency-exchange/internal/entity"
 	apperror "currency-exchange/internal/error"
+	"currency-exchange/internal/iso4217"
 	"currency-exchange/internal/pagination"
 	"currency-exchange/internal/repository"
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"unicode/utf8"
 )
 
+const (
+	CatalogSeed = "seed"
+	CatalogSync = "sync"
+)
+
+// CurrencyFilterMaxCodes caps the number of codes in a listing lookup.
+const CurrencyFilterMaxCodes = 100
+
 type CurrencyService struct {
 	ctx                context.Context
 	currencyRepository repository.CurrencyRepository
@@ -22,38 +32,23 @@ func NewCurrencyService(ctx context.Context, currencyRepository repository.Curre
 	return &CurrencyService{ctx: ctx, currencyRepository: currencyRepository}
 }
 
-func (c *CurrencyService) CreateCurrency(code string, fullName string, sign string) (dto.CurrencyDto, error) {
-	log.Printf("currency_service.create_currency start code=%s", code)
-	if len(code) == 0 || utf8.RuneCountInString(code) > entity.CurrencyCodeMaxLen {
-		log.Printf("currency_service.create_currency validation_error code=%s", code)
-		return dto.CurrencyDto{}, apperror.Validation(
-			"invalid currency code",
-			"code must be 1.."+fmt.Sprint(entity.CurrencyCodeMaxLen)+" symbols",
-		)
-	}
-	if len(sign) == 0 || utf8.RuneCountInString(sign) > entity.CurrencySignMaxLen {
-		log.Printf("currency_service.create_currency validation_error sign=%s", sign)
-		return dto.CurrencyDto{}, apperror.Validation(
-			"invalid currency sign",
-			"sign must be 1.."+fmt.Sprint(entity.CurrencySignMaxLen)+" symbols",
-		)
-	}
-	if utf8.RuneCountInString(fullName) < entity.CurrencyFullNameMinLen ||
-		utf8.RuneCountInString(fullName) > entity.CurrencyFullNameMaxLen {
-		log.Printf("currency_service.create_currency validation_error full_name=%s", fullName)
-		return dto.CurrencyDto{}, apperror.Validation(
-			"invalid currency full name",
-			"full name length must be "+
-				fmt.Sprint(entity.CurrencyFullNameMinLen)+".."+
-				fmt.Sprint(entity.CurrencyFullNameMaxLen)+" symbols",
-		)
+func (c *CurrencyService) CreateCurrency(request dto.CreateCurrencyRequest) (dto.CurrencyDto, error) {
+	code := normalizeCode(request.Code)
+	log.Printf("currency_service.create_currency start code=%s custom=%t", code, request.Custom)
+	currency, err := buildCurrency(code, request.Custom, currencyInput{
+		fullName:    request.FullName,
+		sign:        request.Sign,
+		minorUnits:  request.MinorUnits,
+		numericCode: request.NumericCode,
+		countries:   request.Countries,
+		active:      request.Active,
+		disabled:    request.Disabled,
+	})
+	if err != nil {
+		log.Printf("currency_service.create_currency validation_error code=%s: %v", code, err)
+		return dto.CurrencyDto{}, err
 	}
 
-	currency := entity.Currency{
-		Code:     code,
-		FullName: fullName,
-		Sign:     sign,
-	}
 	id, err := c.currencyRepository.Create(c.ctx, currency)
 	if err != nil {
 		log.Printf("currency_service.create_currency error: %v", err)
@@ -64,7 +59,180 @@ func (c *CurrencyService) CreateCurrency(code string, fullName string, sign stri
 	return mapCurrency(currency), nil
 }
 
+// ReplaceCurrency overwrites every attribute of an existing currency, applying
+// the same defaults and validation rules as CreateCurrency. The disabled
+// state is kept unless the request sets it.
+func (c *CurrencyService) ReplaceCurrency(code string, request dto.UpdateCurrencyRequest) (dto.CurrencyDto, error) {
+	code = normalizeCode(code)
+	log.Printf("currency_service.replace_currency start code=%s", code)
+	existing, err := c.getCurrency(code)
+	if err != nil {
+		return dto.CurrencyDto{}, err
+	}
+
+	disabled := existing.Disabled
+	if request.Disabled != nil {
+		disabled = *request.Disabled
+	}
+	_, known := iso4217.Lookup(code)
+	currency, err := buildCurrency(existing.Code, !known, currencyInput{
+		fullName:    request.FullName,
+		sign:        request.Sign,
+		minorUnits:  request.MinorUnits,
+		numericCode: request.NumericCode,
+		countries:   request.Countries,
+		active:      request.Active,
+		disabled:    &disabled,
+	})
+	if err != nil {
+		log.Printf("currency_service.replace_currency validation_error code=%s: %v", code, err)
+		return dto.CurrencyDto{}, err
+	}
+	currency.ID = existing.ID
+
+	return c.updateCurrency(currency)
+}
+
+// PatchCurrency changes only the attributes present in the request.
+func (c *CurrencyService) PatchCurrency(code string, request dto.PatchCurrencyRequest) (dto.CurrencyDto, error) {
+	code = normalizeCode(code)
+	log.Printf("currency_service.patch_currency start code=%s", code)
+	currency, err := c.getCurrency(code)
+	if err != nil {
+		return dto.CurrencyDto{}, err
+	}
+
+	if request.FullName != nil {
+		currency.FullName = *request.FullName
+	}
+	if request.Sign != nil {
+		currency.Sign = *request.Sign
+	}
+	if request.MinorUnits != nil {
+		currency.MinorUnits = *request.MinorUnits
+	}
+	if request.NumericCode != nil {
+		currency.NumericCode = *request.NumericCode
+	}
+	if request.Countries != nil {
+		currency.Countries = normalizeCountries(*request.Countries)
+	}
+	if request.Active != nil {
+		currency.Active = *request.Active
+	}
+	if request.Disabled != nil {
+		currency.Disabled = *request.Disabled
+	}
+	if err := validateCurrency(currency); err != nil {
+		log.Printf("currency_service.patch_currency validation_error code=%s: %v", code, err)
+		return dto.CurrencyDto{}, err
+	}
+	if err := validateCatalogNumericCode(currency); err != nil {
+		log.Printf("currency_service.patch_currency validation_error code=%s: %v", code, err)
+		return dto.CurrencyDto{}, err
+	}
+
+	return c.updateCurrency(currency)
+}
+
+// DeleteCurrency removes a currency. Without force it fails with a conflict
+// while rates or rate history reference the currency; disabling it via
+// PatchCurrency is the non-destructive alternative.
+func (c *CurrencyService) DeleteCurrency(code string, force bool) error {
+	code = normalizeCode(code)
+	log.Printf("currency_service.delete_currency start code=%s force=%t", code, force)
+	if code == "" {
+		log.Printf("currency_service.delete_currency validation_error: empty code")
+		return apperror.Validation("currency code is required", "empty code")
+	}
+
+	if err := c.currencyRepository.Delete(c.ctx, code, force); err != nil {
+		switch {
+		case errors.Is(err, apperror.ErrNotFound), errors.Is(err, apperror.ErrConflict):
+			log.Printf("currency_service.delete_currency rejected code=%s: %v", code, err)
+			return err
+		default:
+			log.Printf("currency_service.delete_currency error: %v", err)
+			return apperror.Internal("delete currency", err.Error())
+		}
+	}
+	log.Printf("currency_service.delete_currency ok code=%s", code)
+	return nil
+}
+
+func (c *CurrencyService) updateCurrency(currency entity.Currency) (dto.CurrencyDto, error) {
+	if err := c.currencyRepository.Update(c.ctx, currency); err != nil {
+		var notFoundErr *apperror.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			log.Printf("currency_service.update_currency not_found code=%s", currency.Code)
+			return dto.CurrencyDto{}, apperror.NotFound("currency not found", "code="+currency.Code)
+		}
+		log.Printf("currency_service.update_currency error: %v", err)
+		return dto.CurrencyDto{}, apperror.Internal("update currency", err.Error())
+	}
+	log.Printf("currency_service.update_currency ok code=%s", currency.Code)
+	return mapCurrency(currency), nil
+}
+
+func (c *CurrencyService) getCurrency(code string) (entity.Currency, error) {
+	currency, err := c.currencyRepository.GetByCode(c.ctx, code)
+	if err != nil {
+		var notFoundErr *apperror.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			log.Printf("currency_service.get_currency not_found code=%s", code)
+			return entity.Currency{}, apperror.NotFound("currency not found", "code="+code)
+		}
+		log.Printf("currency_service.get_currency error: %v", err)
+		return entity.Currency{}, apperror.Internal("get currency by code", err.Error())
+	}
+	return currency, nil
+}
+
+// SyncCatalog loads the embedded ISO 4217 catalog into the currencies table.
+// CatalogSeed only inserts missing currencies; CatalogSync additionally
+// refreshes the ISO owned metadata of currencies that already exist.
+func (c *CurrencyService) SyncCatalog(mode string) (dto.CatalogSyncDto, error) {
+	log.Printf("currency_service.sync_catalog start mode=%s", mode)
+	if mode != CatalogSeed && mode != CatalogSync {
+		log.Printf("currency_service.sync_catalog validation_error mode=%s", mode)
+		return dto.CatalogSyncDto{}, apperror.Validation(
+			"invalid catalog mode",
+			"mode must be "+CatalogSeed+" or "+CatalogSync,
+		)
+	}
+
+	entries := iso4217.All()
+	currencies := make([]entity.Currency, 0, len(entries))
+	for _, entry := range entries {
+		currencies = append(currencies, entity.Currency{
+			Code:        entry.Code,
+			FullName:    entry.Name,
+			Sign:        entry.Sign,
+			MinorUnits:  entry.MinorUnits,
+			NumericCode: entry.NumericCode,
+			Countries:   entry.Countries,
+			Active:      entry.Active,
+		})
+	}
+
+	inserted, updated, err := c.currencyRepository.Upsert(c.ctx, currencies, mode == CatalogSync)
+	if err != nil {
+		log.Printf("currency_service.sync_catalog error: %v", err)
+		return dto.CatalogSyncDto{}, apperror.Internal("sync currency catalog", err.Error())
+	}
+
+	result := dto.CatalogSyncDto{
+		Mode:      mode,
+		Inserted:  nonNilStrings(inserted),
+		Updated:   nonNilStrings(updated),
+		Unchanged: len(currencies) - len(inserted) - len(updated),
+	}
+	log.Printf("currency_service.sync_catalog ok inserted=%d updated=%d unchanged=%d", len(result.Inserted), len(result.Updated), result.Unchanged)
+	return result, nil
+}
+
 func (c *CurrencyService) GetCurrencyByCode(code string) (dto.CurrencyDto, error) {
+	code = normalizeCode(code)
 	log.Printf("currency_service.get_currency_by_code start code=%s", code)
 	if code == "" {
 		log.Printf("currency_service.get_currency_by_code validation_error: empty code")
@@ -86,7 +254,8 @@ func (c *CurrencyService) GetCurrencyByCode(code string) (dto.CurrencyDto, error
 	return mapCurrency(currency), nil
 }
 
-func (c *CurrencyService) GetAllCurrencyPage(request pagination.PageRequest) (pagination.Page[dto.CurrencyDto], error) {
+// GetAllCurrencyPage lists currencies matching filter, one page at a time.
+func (c *CurrencyService) GetAllCurrencyPage(filter repository.CurrencyFilter, request pagination.PageRequest) (pagination.Page[dto.CurrencyDto], error) {
 	log.Printf("currency_service.get_all_currency_page start page=%d size=%d", request.PageNumber, request.PageSize)
 	if request.PageNumber < 1 || request.PageSize < 1 {
 		log.Printf("currency_service.get_all_currency_page validation_error page=%d size=%d", request.PageNumber, request.PageSize)
@@ -95,8 +264,17 @@ func (c *CurrencyService) GetAllCurrencyPage(request pagination.PageRequest) (pa
 			"pageNumber or pageSize less than 1",
 		)
 	}
+	filter, err := normalizeCurrencyFilter(filter)
+	if err != nil {
+		log.Printf("currency_service.get_all_currency_page validation_error: %v", err)
+		return pagination.Page[dto.CurrencyDto]{}, err
+	}
+	if err := validateCurrencySort(request); err != nil {
+		log.Printf("currency_service.get_all_currency_page validation_error: %v", err)
+		return pagination.Page[dto.CurrencyDto]{}, err
+	}
 
-	page, err := c.currencyRepository.GetPage(c.ctx, request)
+	page, err := c.currencyRepository.GetPage(c.ctx, filter, request)
 	if err != nil {
 		log.Printf("currency_service.get_all_currency_page error: %v", err)
 		return pagination.Page[dto.CurrencyDto]{}, apperror.Internal("get currency page", err.Error())
@@ -104,12 +282,7 @@ func (c *CurrencyService) GetAllCurrencyPage(request pagination.PageRequest) (pa
 
 	items := make([]dto.CurrencyDto, 0, len(page.Items))
 	for _, currency := range page.Items {
-		items = append(items, dto.CurrencyDto{
-			ID:       currency.ID,
-			Code:     currency.Code,
-			FullName: currency.FullName,
-			Sign:     currency.Sign,
-		})
+		items = append(items, mapCurrency(currency))
 	}
 
 	log.Printf("currency_service.get_all_currency_page ok total=%d", page.Total)
@@ -121,11 +294,280 @@ func (c *CurrencyService) GetAllCurrencyPage(request pagination.PageRequest) (pa
 	}, nil
 }
 
+// GetCurrencyCursorPage lists currencies matching filter with keyset
+// pagination, which stays consistent while rows are inserted.
+func (c *CurrencyService) GetCurrencyCursorPage(filter repository.CurrencyFilter, request pagination.CursorRequest) (pagination.CursorPage[dto.CurrencyDto], error) {
+	log.Printf("currency_service.get_currency_cursor_page start size=%d has_cursor=%t", request.PageSize, request.Position != nil)
+	if request.PageSize < 1 {
+		log.Printf("currency_service.get_currency_cursor_page validation_error size=%d", request.PageSize)
+		return pagination.CursorPage[dto.CurrencyDto]{}, apperror.Validation(
+			"pageSize must be greater than zero",
+			"pageSize less than 1",
+		)
+	}
+	filter, err := normalizeCurrencyFilter(filter)
+	if err != nil {
+		log.Printf("currency_service.get_currency_cursor_page validation_error: %v", err)
+		return pagination.CursorPage[dto.CurrencyDto]{}, err
+	}
+	sort := pagination.PageRequest{SortBy: request.SortBy, Direction: request.Direction}
+	if request.Position != nil {
+		sort = pagination.PageRequest{SortBy: request.Position.SortBy, Direction: request.Position.Direction}
+	}
+	if err := validateCurrencySort(sort); err != nil {
+		log.Printf("currency_service.get_currency_cursor_page validation_error: %v", err)
+		return pagination.CursorPage[dto.CurrencyDto]{}, err
+	}
+
+	page, err := c.currencyRepository.GetCursorPage(c.ctx, filter, request)
+	if err != nil {
+		log.Printf("currency_service.get_currency_cursor_page error: %v", err)
+		return pagination.CursorPage[dto.CurrencyDto]{}, apperror.Internal("get currency cursor page", err.Error())
+	}
+
+	items := make([]dto.CurrencyDto, 0, len(page.Items))
+	for _, currency := range page.Items {
+		items = append(items, mapCurrency(currency))
+	}
+
+	log.Printf("currency_service.get_currency_cursor_page ok count=%d", len(items))
+	return pagination.CursorPage[dto.CurrencyDto]{
+		Items:    items,
+		PageSize: page.PageSize,
+		Next:     page.Next,
+		Prev:     page.Prev,
+	}, nil
+}
+
+type currencyInput struct {
+	fullName    string
+	sign        string
+	minorUnits  *int32
+	numericCode string
+	countries   []string
+	active      *bool
+	disabled    *bool
+}
+
+// buildCurrency assembles a currency from user input. Codes outside the ISO
+// 4217 catalog are rejected unless custom is set; for catalog codes omitted
+// metadata is filled in from the catalog.
+func buildCurrency(code string, custom bool, input currencyInput) (entity.Currency, error) {
+	currency := entity.Currency{
+		Code:        code,
+		FullName:    input.fullName,
+		Sign:        input.sign,
+		MinorUnits:  entity.CurrencyDefaultMinorUnits,
+		NumericCode: input.numericCode,
+		Countries:   normalizeCountries(input.countries),
+		Active:      true,
+	}
+
+	entry, known := iso4217.Lookup(code)
+	if !known && !custom {
+		return entity.Currency{}, apperror.Validation(
+			"unknown currency code",
+			"code "+code+" is not in ISO 4217; set custom to create a private code",
+		)
+	}
+	if known {
+		if currency.NumericCode == "" {
+			currency.NumericCode = entry.NumericCode
+		}
+		currency.MinorUnits = entry.MinorUnits
+		if len(currency.Countries) == 0 {
+			currency.Countries = entry.Countries
+		}
+		currency.Active = entry.Active
+	}
+	if input.minorUnits != nil {
+		currency.MinorUnits = *input.minorUnits
+	}
+	if input.active != nil {
+		currency.Active = *input.active
+	}
+	if input.disabled != nil {
+		currency.Disabled = *input.disabled
+	}
+	if err := validateCurrency(currency); err != nil {
+		return entity.Currency{}, err
+	}
+	if !custom {
+		if err := validateCatalogNumericCode(currency); err != nil {
+			return entity.Currency{}, err
+		}
+	}
+	return currency, nil
+}
+
+// validateCatalogNumericCode rejects a numeric code that contradicts the ISO
+// 4217 catalog. Private codes are not checked.
+func validateCatalogNumericCode(currency entity.Currency) error {
+	entry, known := iso4217.Lookup(currency.Code)
+	if !known || currency.NumericCode == "" || currency.NumericCode == entry.NumericCode {
+		return nil
+	}
+	return apperror.Validation(
+		"invalid currency numeric code",
+		"ISO 4217 numeric code of "+currency.Code+" is "+entry.NumericCode,
+	)
+}
+
+func validateCurrency(currency entity.Currency) error {
+	if len(currency.Code) == 0 || utf8.RuneCountInString(currency.Code) > entity.CurrencyCodeMaxLen {
+		return apperror.Validation(
+			"invalid currency code",
+			"code must be 1.."+fmt.Sprint(entity.CurrencyCodeMaxLen)+" symbols",
+		)
+	}
+	if len(currency.Sign) == 0 || utf8.RuneCountInString(currency.Sign) > entity.CurrencySignMaxLen {
+		return apperror.Validation(
+			"invalid currency sign",
+			"sign must be 1.."+fmt.Sprint(entity.CurrencySignMaxLen)+" symbols",
+		)
+	}
+	if utf8.RuneCountInString(currency.FullName) < entity.CurrencyFullNameMinLen ||
+		utf8.RuneCountInString(currency.FullName) > entity.CurrencyFullNameMaxLen {
+		return apperror.Validation(
+			"invalid currency full name",
+			"full name length must be "+
+				fmt.Sprint(entity.CurrencyFullNameMinLen)+".."+
+				fmt.Sprint(entity.CurrencyFullNameMaxLen)+" symbols",
+		)
+	}
+	if currency.MinorUnits < 0 || currency.MinorUnits > entity.CurrencyMaxMinorUnits {
+		return apperror.Validation(
+			"invalid currency minor units",
+			"minor units must be 0.."+fmt.Sprint(entity.CurrencyMaxMinorUnits),
+		)
+	}
+	if currency.NumericCode != "" && !isDigits(currency.NumericCode, entity.CurrencyNumericCodeLen) {
+		return apperror.Validation(
+			"invalid currency numeric code",
+			"numeric code must be "+fmt.Sprint(entity.CurrencyNumericCodeLen)+" digits",
+		)
+	}
+	for _, country := range currency.Countries {
+		if !isUpperLetters(country, entity.CountryCodeLen) {
+			return apperror.Validation(
+				"invalid country code",
+				"country "+country+" must be an ISO 3166-1 alpha-2 code",
+			)
+		}
+	}
+	return nil
+}
+
+// normalizeCurrencyFilter trims the search term and normalizes the codes of
+// a listing filter.
+func normalizeCurrencyFilter(filter repository.CurrencyFilter) (repository.CurrencyFilter, error) {
+	filter.Search = strings.TrimSpace(filter.Search)
+	if utf8.RuneCountInString(filter.Search) > entity.CurrencyFullNameMaxLen {
+		return repository.CurrencyFilter{}, apperror.Validation(
+			"search term is too long",
+			"search must be at most "+fmt.Sprint(entity.CurrencyFullNameMaxLen)+" symbols",
+		)
+	}
+	if len(filter.Codes) > CurrencyFilterMaxCodes {
+		return repository.CurrencyFilter{}, apperror.Validation(
+			"too many currency codes",
+			"codes supports no more than "+fmt.Sprint(CurrencyFilterMaxCodes)+" currencies",
+		)
+	}
+	codes := make([]string, 0, len(filter.Codes))
+	for _, code := range filter.Codes {
+		if code = normalizeCode(code); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	filter.Codes = codes
+	return filter, nil
+}
+
+func validateCurrencySort(request pagination.PageRequest) error {
+	switch request.SortBy {
+	case "", repository.CurrencySortID, repository.CurrencySortCode, repository.CurrencySortName:
+	default:
+		return apperror.Validation(
+			"invalid sort field",
+			"sort must be "+repository.CurrencySortID+", "+repository.CurrencySortCode+" or "+repository.CurrencySortName,
+		)
+	}
+	if request.Direction != "" && request.Direction != pagination.SortAsc && request.Direction != pagination.SortDesc {
+		return apperror.Validation(
+			"invalid sort direction",
+			"order must be "+string(pagination.SortAsc)+" or "+string(pagination.SortDesc),
+		)
+	}
+	return nil
+}
+
+// normalizeCode trims and upper-cases a currency code.
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// normalizeCountries upper-cases country codes and drops blanks and
+// duplicates.
+func normalizeCountries(countries []string) []string {
+	normalized := make([]string, 0, len(countries))
+	seen := make(map[string]bool)
+	for _, country := range countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if country == "" || seen[country] {
+			continue
+		}
+		seen[country] = true
+		normalized = append(normalized, country)
+	}
+	return normalized
+}
+
+func isDigits(value string, length int) bool {
+	if len(value) != length {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isUpperLetters(value string, length int) bool {
+	if len(value) != length {
+		return false
+	}
+	for _, r := range value {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
 func mapCurrency(currency entity.Currency) dto.CurrencyDto {
+	countries := currency.Countries
+	if countries == nil {
+		countries = []string{}
+	}
 	return dto.CurrencyDto{
-		ID:       currency.ID,
-		Code:     currency.Code,
-		FullName: currency.FullName,
-		Sign:     currency.Sign,
+		ID:          currency.ID,
+		Code:        currency.Code,
+		FullName:    currency.FullName,
+		Sign:        currency.Sign,
+		MinorUnits:  currency.MinorUnits,
+		NumericCode: currency.NumericCode,
+		Countries:   countries,
+		Active:      currency.Active,
+		Disabled:    currency.Disabled,
 	}
 }
@@ -2,57 +2,121 @@ package service
 
 import (
 	"context"
+	"currency-exchange/internal/conversion"
 	"currency-exchange/internal/dto"
 	"currency-exchange/internal/entity"
 	apperror "currency-exchange/internal/error"
+	"currency-exchange/internal/pagination"
 	"currency-exchange/internal/repository"
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
 
+const (
+	// ExchangeBatchMaxItems caps the number of conversions in a single batch.
+	ExchangeBatchMaxItems = 10000
+	// RateMatrixMaxCodes caps the size of a cross-rate matrix.
+	RateMatrixMaxCodes = 100
+	// AnonymousActor is recorded for changes made without naming an actor.
+	AnonymousActor = "anonymous"
+)
+
+// ExchangeConfig holds the tunables of the conversion engine.
+type ExchangeConfig struct {
+	// MaxHops limits how many stored rates may be chained to reach the
+	// target currency.
+	MaxHops int
+	// RoundingMode is used when a conversion does not ask for one.
+	RoundingMode conversion.RoundingMode
+	// Freshness decides what happens to conversions over old rates.
+	Freshness FreshnessPolicy
+	// Guardrail limits how far a single write may move a rate.
+	Guardrail GuardrailPolicy
+}
+
+// ExchangeOptions tune a single conversion request.
+type ExchangeOptions struct {
+	// At selects the rates in force at that moment; nil means current rates.
+	At *time.Time
+	// Rounding selects how converted amounts are rounded to the minor units
+	// of the target currency; empty means the configured default.
+	Rounding conversion.RoundingMode
+}
+
 type ExchangeService struct {
 	ctx                context.Context
 	exchangeRepository repository.ExchangeRepository
 	currencyRepository repository.CurrencyRepository
+	config             ExchangeConfig
 }
 
 func NewExchangeService(
 	ctx context.Context,
 	exchangeRepository repository.ExchangeRepository,
 	currencyRepository repository.CurrencyRepository,
+	config ExchangeConfig,
 ) *ExchangeService {
+	if config.MaxHops < 1 {
+		config.MaxHops = conversion.DefaultMaxHops
+	}
+	if config.RoundingMode == "" {
+		config.RoundingMode = conversion.RoundHalfUp
+	}
+	if config.Freshness.Mode == "" {
+		config.Freshness.Mode = FreshnessWarn
+	}
 	return &ExchangeService{
 		ctx:                ctx,
 		exchangeRepository: exchangeRepository,
 		currencyRepository: currencyRepository,
+		config:             config,
 	}
 }
 
-func (s *ExchangeService) CreateRate(baseCode string, targetCode string, rate decimal.Decimal) (dto.ExchangeRateDto, error) {
+// CreateRate adds the rate of a new pair. options name the actor and may
+// override the rate change guardrail.
+func (s *ExchangeService) CreateRate(
+	baseCode string,
+	targetCode string,
+	rate decimal.Decimal,
+	options RateWriteOptions,
+) (dto.ExchangeRateDto, error) {
 	log.Printf("exchange_service.create_rate start base=%s target=%s", baseCode, targetCode)
-	if err := validateRatePrecision(rate); err != nil {
+	if err := validateRate(rate); err != nil {
 		log.Printf("exchange_service.create_rate validation_error: %v", err)
 		return dto.ExchangeRateDto{}, err
 	}
-	baseCurrency, err := s.currencyRepository.GetByCode(s.ctx, baseCode)
+	options, err := normalizeRateWriteOptions(options)
 	if err != nil {
-		return dto.ExchangeRateDto{}, s.wrapCurrencyError("base currency", baseCode, err)
+		log.Printf("exchange_service.create_rate validation_error: %v", err)
+		return dto.ExchangeRateDto{}, err
 	}
-	targetCurrency, err := s.currencyRepository.GetByCode(s.ctx, targetCode)
+	baseCurrency, targetCurrency, err := s.getPairCurrencies(baseCode, targetCode)
 	if err != nil {
-		return dto.ExchangeRateDto{}, s.wrapCurrencyError("target currency", targetCode, err)
+		return dto.ExchangeRateDto{}, err
 	}
 
+	now := time.Now().UTC()
 	entityRate := entity.ExchangeRate{
 		BaseCurrency:   baseCurrency,
 		TargetCurrency: targetCurrency,
 		Rate:           rate,
+		EffectiveFrom:  now,
+		UpdatedAt:      now,
+	}
+	if err := s.guardSingleRate(&entityRate, options); err != nil {
+		return dto.ExchangeRateDto{}, err
 	}
 	id, err := s.exchangeRepository.Create(s.ctx, entityRate)
 	if err != nil {
+		if errors.Is(err, apperror.ErrConflict) {
+			log.Printf("exchange_service.create_rate conflict base=%s target=%s", baseCurrency.Code, targetCurrency.Code)
+			return dto.ExchangeRateDto{}, err
+		}
 		log.Printf("exchange_service.create_rate error: %v", err)
 		return dto.ExchangeRateDto{}, apperror.Internal("create exchange rate", err.Error())
 	}
@@ -61,26 +125,41 @@ func (s *ExchangeService) CreateRate(baseCode string, targetCode string, rate de
 	return mapRate(entityRate), nil
 }
 
-func (s *ExchangeService) UpdateRate(id int64, baseCode string, targetCode string, rate decimal.Decimal) (dto.ExchangeRateDto, error) {
+// UpdateRate replaces a stored rate. options name the actor and may
+// override the rate change guardrail.
+func (s *ExchangeService) UpdateRate(
+	id int64,
+	baseCode string,
+	targetCode string,
+	rate decimal.Decimal,
+	options RateWriteOptions,
+) (dto.ExchangeRateDto, error) {
 	log.Printf("exchange_service.update_rate start id=%d", id)
-	if err := validateRatePrecision(rate); err != nil {
+	if err := validateRate(rate); err != nil {
 		log.Printf("exchange_service.update_rate validation_error: %v", err)
 		return dto.ExchangeRateDto{}, err
 	}
-	baseCurrency, err := s.currencyRepository.GetByCode(s.ctx, baseCode)
+	options, err := normalizeRateWriteOptions(options)
 	if err != nil {
-		return dto.ExchangeRateDto{}, s.wrapCurrencyError("base currency", baseCode, err)
+		log.Printf("exchange_service.update_rate validation_error: %v", err)
+		return dto.ExchangeRateDto{}, err
 	}
-	targetCurrency, err := s.currencyRepository.GetByCode(s.ctx, targetCode)
+	baseCurrency, targetCurrency, err := s.getPairCurrencies(baseCode, targetCode)
 	if err != nil {
-		return dto.ExchangeRateDto{}, s.wrapCurrencyError("target currency", targetCode, err)
+		return dto.ExchangeRateDto{}, err
 	}
 
+	now := time.Now().UTC()
 	entityRate := entity.ExchangeRate{
 		ID:             id,
 		BaseCurrency:   baseCurrency,
 		TargetCurrency: targetCurrency,
 		Rate:           rate,
+		EffectiveFrom:  now,
+		UpdatedAt:      now,
+	}
+	if err := s.guardSingleRate(&entityRate, options); err != nil {
+		return dto.ExchangeRateDto{}, err
 	}
 	if err := s.exchangeRepository.Update(s.ctx, entityRate); err != nil {
 		var notFoundErr *apperror.NotFoundError
@@ -88,6 +167,10 @@ func (s *ExchangeService) UpdateRate(id int64, baseCode string, targetCode strin
 			log.Printf("exchange_service.update_rate not_found id=%d", id)
 			return dto.ExchangeRateDto{}, apperror.NotFound("exchange rate not found", "id="+fmt.Sprint(id))
 		}
+		if errors.Is(err, apperror.ErrConflict) {
+			log.Printf("exchange_service.update_rate conflict id=%d", id)
+			return dto.ExchangeRateDto{}, err
+		}
 		log.Printf("exchange_service.update_rate error: %v", err)
 		return dto.ExchangeRateDto{}, apperror.Internal("update exchange rate", err.Error())
 	}
@@ -96,6 +179,98 @@ func (s *ExchangeService) UpdateRate(id int64, baseCode string, targetCode strin
 	return mapRate(entityRate), nil
 }
 
+// UpsertRate sets the rate of a currency pair, creating the pair when it
+// does not exist yet. created reports which of the two happened. options
+// name the actor and may override the rate change guardrail.
+func (s *ExchangeService) UpsertRate(
+	baseCode string,
+	targetCode string,
+	rate decimal.Decimal,
+	options RateWriteOptions,
+) (dto.ExchangeRateDto, bool, error) {
+	log.Printf("exchange_service.upsert_rate start base=%s target=%s", baseCode, targetCode)
+	if err := validateRate(rate); err != nil {
+		log.Printf("exchange_service.upsert_rate validation_error: %v", err)
+		return dto.ExchangeRateDto{}, false, err
+	}
+	options, err := normalizeRateWriteOptions(options)
+	if err != nil {
+		log.Printf("exchange_service.upsert_rate validation_error: %v", err)
+		return dto.ExchangeRateDto{}, false, err
+	}
+	baseCurrency, targetCurrency, err := s.getPairCurrencies(baseCode, targetCode)
+	if err != nil {
+		return dto.ExchangeRateDto{}, false, err
+	}
+
+	now := time.Now().UTC()
+	entityRate := entity.ExchangeRate{
+		BaseCurrency:   baseCurrency,
+		TargetCurrency: targetCurrency,
+		Rate:           rate,
+		EffectiveFrom:  now,
+		UpdatedAt:      now,
+	}
+	if err := s.guardSingleRate(&entityRate, options); err != nil {
+		return dto.ExchangeRateDto{}, false, err
+	}
+	id, created, err := s.exchangeRepository.Upsert(s.ctx, entityRate)
+	if err != nil {
+		log.Printf("exchange_service.upsert_rate error: %v", err)
+		return dto.ExchangeRateDto{}, false, apperror.Internal("upsert exchange rate", err.Error())
+	}
+	entityRate.ID = id
+	log.Printf("exchange_service.upsert_rate ok id=%d created=%t", id, created)
+	return mapRate(entityRate), created, nil
+}
+
+// GetRateByPair returns the stored rate of a currency pair. Unlike Exchange it
+// never inverts or triangulates.
+func (s *ExchangeService) GetRateByPair(baseCode string, targetCode string) (dto.ExchangeRateDto, error) {
+	log.Printf("exchange_service.get_rate_by_pair start base=%s target=%s", baseCode, targetCode)
+	baseCurrency, targetCurrency, err := s.getPairCurrencies(baseCode, targetCode)
+	if err != nil {
+		return dto.ExchangeRateDto{}, err
+	}
+
+	rate, err := s.exchangeRepository.GetByPair(s.ctx, baseCurrency.ID, targetCurrency.ID)
+	if err != nil {
+		var notFoundErr *apperror.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			log.Printf("exchange_service.get_rate_by_pair not_found base=%s target=%s", baseCurrency.Code, targetCurrency.Code)
+			return dto.ExchangeRateDto{}, apperror.NotFound(
+				"exchange rate not found",
+				"base="+baseCurrency.Code+" target="+targetCurrency.Code,
+			)
+		}
+		log.Printf("exchange_service.get_rate_by_pair error: %v", err)
+		return dto.ExchangeRateDto{}, apperror.Internal("get exchange rate by pair", err.Error())
+	}
+	log.Printf("exchange_service.get_rate_by_pair ok id=%d", rate.ID)
+	return mapRate(rate), nil
+}
+
+func (s *ExchangeService) getPairCurrencies(baseCode string, targetCode string) (entity.Currency, entity.Currency, error) {
+	baseCode, targetCode = normalizeCode(baseCode), normalizeCode(targetCode)
+	if baseCode == "" || targetCode == "" {
+		log.Printf("exchange_service.get_pair validation_error: empty code")
+		return entity.Currency{}, entity.Currency{}, apperror.Validation("currency codes are required", "base or target code is empty")
+	}
+	if baseCode == targetCode {
+		log.Printf("exchange_service.get_pair validation_error: same code=%s", baseCode)
+		return entity.Currency{}, entity.Currency{}, apperror.Validation("base and target currency must differ", "code="+baseCode)
+	}
+	baseCurrency, err := s.currencyRepository.GetByCode(s.ctx, baseCode)
+	if err != nil {
+		return entity.Currency{}, entity.Currency{}, s.wrapCurrencyError("base currency", baseCode, err)
+	}
+	targetCurrency, err := s.currencyRepository.GetByCode(s.ctx, targetCode)
+	if err != nil {
+		return entity.Currency{}, entity.Currency{}, s.wrapCurrencyError("target currency", targetCode, err)
+	}
+	return baseCurrency, targetCurrency, nil
+}
+
 func (s *ExchangeService) GetRateByID(id int64) (dto.ExchangeRateDto, error) {
 	log.Printf("exchange_service.get_rate_by_id start id=%d", id)
 	rate, err := s.exchangeRepository.GetByID(s.ctx, id)
@@ -112,12 +287,357 @@ func (s *ExchangeService) GetRateByID(id int64) (dto.ExchangeRateDto, error) {
 	return mapRate(rate), nil
 }
 
+// DeleteRate removes a rate from the rate book. The deletion is appended to
+// the rate history together with actor, so the history of a deleted rate
+// stays available.
+func (s *ExchangeService) DeleteRate(id int64, actor string) error {
+	log.Printf("exchange_service.delete_rate start id=%d actor=%s", id, actor)
+	actor, err := normalizeActor(actor)
+	if err != nil {
+		log.Printf("exchange_service.delete_rate validation_error: %v", err)
+		return err
+	}
+
+	if err := s.exchangeRepository.Delete(s.ctx, id, actor, time.Now().UTC()); err != nil {
+		var notFoundErr *apperror.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			log.Printf("exchange_service.delete_rate not_found id=%d", id)
+			return apperror.NotFound("exchange rate not found", "id="+fmt.Sprint(id))
+		}
+		log.Printf("exchange_service.delete_rate error: %v", err)
+		return apperror.Internal("delete exchange rate", err.Error())
+	}
+	log.Printf("exchange_service.delete_rate ok id=%d", id)
+	return nil
+}
+
+// GetRateHistory lists every version of a rate, newest first. It keeps
+// working after the rate has been deleted.
+func (s *ExchangeService) GetRateHistory(id int64) ([]dto.ExchangeRateVersionDto, error) {
+	log.Printf("exchange_service.get_rate_history start id=%d", id)
+	versions, err := s.exchangeRepository.GetHistory(s.ctx, id)
+	if err != nil {
+		log.Printf("exchange_service.get_rate_history error: %v", err)
+		return nil, apperror.Internal("get exchange rate history", err.Error())
+	}
+	if len(versions) == 0 {
+		log.Printf("exchange_service.get_rate_history not_found id=%d", id)
+		return nil, apperror.NotFound("exchange rate not found", "id="+fmt.Sprint(id))
+	}
+
+	items := make([]dto.ExchangeRateVersionDto, 0, len(versions))
+	for _, version := range versions {
+		items = append(items, dto.ExchangeRateVersionDto{
+			ID:             version.ID,
+			ExchangeRateID: version.ExchangeRateID,
+			BaseCurrency:   mapCurrency(version.BaseCurrency),
+			TargetCurrency: mapCurrency(version.TargetCurrency),
+			Rate:           version.Rate,
+			EffectiveFrom:  version.EffectiveFrom,
+			RecordedAt:     version.RecordedAt,
+			Deleted:        version.Deleted,
+			ChangedBy:      version.ChangedBy,
+			Sources:        version.Sources,
+			OverrideReason: version.OverrideReason,
+		})
+	}
+	log.Printf("exchange_service.get_rate_history ok id=%d count=%d", id, len(items))
+	return items, nil
+}
+
+// GetRatePage lists the rate book matching filter, one page at a time.
+func (s *ExchangeService) GetRatePage(filter repository.RateFilter, request pagination.PageRequest) (pagination.Page[dto.ExchangeRateDto], error) {
+	log.Printf("exchange_service.get_rate_page start page=%d size=%d", request.PageNumber, request.PageSize)
+	if request.PageNumber < 1 || request.PageSize < 1 {
+		log.Printf("exchange_service.get_rate_page validation_error page=%d size=%d", request.PageNumber, request.PageSize)
+		return pagination.Page[dto.ExchangeRateDto]{}, apperror.Validation(
+			"pageNumber and pageSize must be greater than zero",
+			"pageNumber or pageSize less than 1",
+		)
+	}
+	if err := validateRateSort(request.SortBy, request.Direction); err != nil {
+		log.Printf("exchange_service.get_rate_page validation_error: %v", err)
+		return pagination.Page[dto.ExchangeRateDto]{}, err
+	}
+
+	page, err := s.exchangeRepository.GetPage(s.ctx, normalizeRateFilter(filter), request)
+	if err != nil {
+		log.Printf("exchange_service.get_rate_page error: %v", err)
+		return pagination.Page[dto.ExchangeRateDto]{}, apperror.Internal("get exchange rate page", err.Error())
+	}
+
+	items := make([]dto.ExchangeRateDto, 0, len(page.Items))
+	for _, rate := range page.Items {
+		items = append(items, mapRate(rate))
+	}
+	log.Printf("exchange_service.get_rate_page ok total=%d", page.Total)
+	return pagination.Page[dto.ExchangeRateDto]{
+		Items:      items,
+		PageNumber: page.PageNumber,
+		PageSize:   page.PageSize,
+		Total:      page.Total,
+	}, nil
+}
+
+// GetRateCursorPage lists the rate book matching filter with keyset
+// pagination.
+func (s *ExchangeService) GetRateCursorPage(filter repository.RateFilter, request pagination.CursorRequest) (pagination.CursorPage[dto.ExchangeRateDto], error) {
+	log.Printf("exchange_service.get_rate_cursor_page start size=%d has_cursor=%t", request.PageSize, request.Position != nil)
+	if request.PageSize < 1 {
+		log.Printf("exchange_service.get_rate_cursor_page validation_error size=%d", request.PageSize)
+		return pagination.CursorPage[dto.ExchangeRateDto]{}, apperror.Validation(
+			"pageSize must be greater than zero",
+			"pageSize less than 1",
+		)
+	}
+	sortBy, direction := request.SortBy, request.Direction
+	if request.Position != nil {
+		sortBy, direction = request.Position.SortBy, request.Position.Direction
+	}
+	if err := validateRateSort(sortBy, direction); err != nil {
+		log.Printf("exchange_service.get_rate_cursor_page validation_error: %v", err)
+		return pagination.CursorPage[dto.ExchangeRateDto]{}, err
+	}
+
+	page, err := s.exchangeRepository.GetCursorPage(s.ctx, normalizeRateFilter(filter), request)
+	if err != nil {
+		log.Printf("exchange_service.get_rate_cursor_page error: %v", err)
+		return pagination.CursorPage[dto.ExchangeRateDto]{}, apperror.Internal("get exchange rate cursor page", err.Error())
+	}
+
+	items := make([]dto.ExchangeRateDto, 0, len(page.Items))
+	for _, rate := range page.Items {
+		items = append(items, mapRate(rate))
+	}
+	log.Printf("exchange_service.get_rate_cursor_page ok count=%d", len(items))
+	return pagination.CursorPage[dto.ExchangeRateDto]{
+		Items:    items,
+		PageSize: page.PageSize,
+		Next:     page.Next,
+		Prev:     page.Prev,
+	}, nil
+}
+
+// Exchange converts amount from baseCode to targetCode and rounds the result
+// to the minor units of the target currency. When options.At is set the
+// conversion uses the rates that were in force at that moment instead of the
+// current ones.
 func (s *ExchangeService) Exchange(
 	baseCode string,
 	targetCode string,
 	amount decimal.Decimal,
+	options ExchangeOptions,
 ) (dto.ExchangeDto, error) {
 	log.Printf("exchange_service.exchange start base=%s target=%s amount=%s", baseCode, targetCode, amount.String())
+	result, err := s.convert(s.newRateResolver(options.At), s.roundingMode(options), baseCode, targetCode, amount)
+	if err != nil {
+		return dto.ExchangeDto{}, err
+	}
+	log.Printf("exchange_service.exchange ok base=%s target=%s amount=%s converted=%s", baseCode, targetCode, amount.String(), result.ConvertAmount.String())
+	return result, nil
+}
+
+// ExchangeBatch converts every item independently and reports a result or an
+// error per item. Currencies, the rate book and conversion paths are resolved
+// once for the whole batch.
+func (s *ExchangeService) ExchangeBatch(items []dto.ExchangeBatchItemRequest, options ExchangeOptions) (dto.ExchangeBatchDto, error) {
+	log.Printf("exchange_service.exchange_batch start items=%d", len(items))
+	if len(items) == 0 {
+		log.Printf("exchange_service.exchange_batch validation_error: empty batch")
+		return dto.ExchangeBatchDto{}, apperror.Validation("batch must contain at least one item", "items is empty")
+	}
+	if len(items) > ExchangeBatchMaxItems {
+		log.Printf("exchange_service.exchange_batch validation_error: items=%d", len(items))
+		return dto.ExchangeBatchDto{}, apperror.Validation(
+			"batch is too large",
+			"batch must contain no more than "+fmt.Sprint(ExchangeBatchMaxItems)+" items",
+		)
+	}
+
+	resolver := s.newRateResolver(options.At)
+	rounding := s.roundingMode(options)
+	result := dto.ExchangeBatchDto{Items: make([]dto.ExchangeBatchItemDto, 0, len(items))}
+	for _, item := range items {
+		converted, err := s.convert(resolver, rounding, item.Base, item.Target, item.Amount)
+		if err != nil {
+			log.Printf("exchange_service.exchange_batch item_error ref=%s: %v", item.Ref, err)
+			result.Items = append(result.Items, dto.ExchangeBatchItemDto{
+				Ref:   item.Ref,
+				Error: &dto.BatchErrorDto{Kind: apperror.KindOf(err), Message: apperror.MessageOf(err)},
+			})
+			result.Failed++
+			continue
+		}
+		result.Items = append(result.Items, dto.ExchangeBatchItemDto{Ref: item.Ref, Result: &converted})
+		result.Succeeded++
+	}
+
+	log.Printf("exchange_service.exchange_batch ok succeeded=%d failed=%d", result.Succeeded, result.Failed)
+	return result, nil
+}
+
+// ExchangeAll converts amount from baseCode into every other active currency
+// that can be reached over the rate book. Currencies without a path are
+// listed in Unreachable, those reached only over rates the freshness policy
+// rejects in StaleTargets.
+func (s *ExchangeService) ExchangeAll(baseCode string, amount decimal.Decimal, options ExchangeOptions) (dto.ExchangeAllDto, error) {
+	log.Printf("exchange_service.exchange_all start base=%s amount=%s", baseCode, amount.String())
+	if baseCode == "" {
+		log.Printf("exchange_service.exchange_all validation_error: empty code")
+		return dto.ExchangeAllDto{}, apperror.Validation("base currency code is required", "base code is empty")
+	}
+	if amount.LessThanOrEqual(decimal.Zero) {
+		log.Printf("exchange_service.exchange_all validation_error: non_positive amount=%s", amount.String())
+		return dto.ExchangeAllDto{}, apperror.Validation("amount must be greater than zero", "amount="+amount.String())
+	}
+	if err := validateAmountPrecision(amount); err != nil {
+		log.Printf("exchange_service.exchange_all validation_error: %v", err)
+		return dto.ExchangeAllDto{}, err
+	}
+
+	resolver := s.newRateResolver(options.At)
+	rounding := s.roundingMode(options)
+	baseCurrency, err := resolver.activeCurrency("base currency", baseCode)
+	if err != nil {
+		return dto.ExchangeAllDto{}, err
+	}
+	currencies, err := s.currencyRepository.GetAll(s.ctx)
+	if err != nil {
+		log.Printf("exchange_service.exchange_all currencies_error: %v", err)
+		return dto.ExchangeAllDto{}, apperror.Internal("get currencies", err.Error())
+	}
+
+	result := dto.ExchangeAllDto{
+		BaseCurrency: mapCurrency(baseCurrency),
+		Amount:       amount,
+		Items:        make([]dto.ExchangeAllItemDto, 0, len(currencies)),
+		Unreachable:  make([]string, 0),
+		RoundingMode: string(rounding),
+		AsOf:         options.At,
+	}
+	for _, target := range currencies {
+		if target.ID == baseCurrency.ID || target.Disabled {
+			continue
+		}
+		path, err := resolver.getRate(baseCurrency, target)
+		var stale bool
+		if err == nil {
+			stale, err = resolver.checkFreshness(path)
+		}
+		if errors.Is(err, apperror.ErrStale) {
+			result.StaleTargets = append(result.StaleTargets, target.Code)
+			continue
+		}
+		if err != nil {
+			var notFoundErr *apperror.NotFoundError
+			if errors.As(err, &notFoundErr) {
+				result.Unreachable = append(result.Unreachable, target.Code)
+				continue
+			}
+			return dto.ExchangeAllDto{}, err
+		}
+		rate := path.Rate()
+		raw := amount.Mul(rate)
+		result.Items = append(result.Items, dto.ExchangeAllItemDto{
+			TargetCurrency:   mapCurrency(target),
+			Rate:             rate,
+			ConvertAmount:    conversion.Round(raw, target.MinorUnits, rounding),
+			RawConvertAmount: raw,
+			Stale:            stale,
+		})
+	}
+
+	log.Printf("exchange_service.exchange_all ok base=%s reachable=%d unreachable=%d", baseCode, len(result.Items), len(result.Unreachable))
+	return result, nil
+}
+
+// GetRateMatrix builds the N x N cross-rate table for the given codes, or for
+// every active currency when codes is empty. Cells relying on a stale rate
+// are flagged; under the reject policy they are left unreachable.
+func (s *ExchangeService) GetRateMatrix(codes []string, at *time.Time) (dto.RateMatrixDto, error) {
+	log.Printf("exchange_service.get_rate_matrix start codes=%d", len(codes))
+	if len(codes) > RateMatrixMaxCodes {
+		log.Printf("exchange_service.get_rate_matrix validation_error: codes=%d", len(codes))
+		return dto.RateMatrixDto{}, apperror.Validation(
+			"too many currencies for a matrix",
+			"matrix supports no more than "+fmt.Sprint(RateMatrixMaxCodes)+" currencies",
+		)
+	}
+	resolver := s.newRateResolver(at)
+
+	var currencies []entity.Currency
+	if len(codes) == 0 {
+		all, err := s.currencyRepository.GetAll(s.ctx)
+		if err != nil {
+			log.Printf("exchange_service.get_rate_matrix currencies_error: %v", err)
+			return dto.RateMatrixDto{}, apperror.Internal("get currencies", err.Error())
+		}
+		for _, currency := range all {
+			if !currency.Disabled {
+				currencies = append(currencies, currency)
+			}
+		}
+	} else {
+		for _, code := range codes {
+			currency, err := resolver.currency("currency", code)
+			if err != nil {
+				return dto.RateMatrixDto{}, err
+			}
+			currencies = append(currencies, currency)
+		}
+	}
+	if len(currencies) > RateMatrixMaxCodes {
+		log.Printf("exchange_service.get_rate_matrix validation_error: codes=%d", len(currencies))
+		return dto.RateMatrixDto{}, apperror.Validation(
+			"too many currencies for a matrix",
+			"matrix supports no more than "+fmt.Sprint(RateMatrixMaxCodes)+" currencies",
+		)
+	}
+
+	result := dto.RateMatrixDto{
+		Codes: make([]string, 0, len(currencies)),
+		Rows:  make([]dto.RateMatrixRowDto, 0, len(currencies)),
+		AsOf:  at,
+	}
+	for _, base := range currencies {
+		result.Codes = append(result.Codes, base.Code)
+		row := dto.RateMatrixRowDto{Base: base.Code, Rates: make([]dto.RateMatrixCellDto, 0, len(currencies))}
+		for _, target := range currencies {
+			cell := dto.RateMatrixCellDto{Target: target.Code}
+			path, err := resolver.getRate(base, target)
+			var stale bool
+			if err == nil {
+				stale, err = resolver.checkFreshness(path)
+			}
+			var notFoundErr *apperror.NotFoundError
+			switch {
+			case errors.Is(err, apperror.ErrStale):
+				cell.Stale = true
+			case errors.As(err, &notFoundErr):
+			case err != nil:
+				return dto.RateMatrixDto{}, err
+			default:
+				rate := path.Rate()
+				cell.Rate = &rate
+				cell.Reachable = true
+				cell.Stale = stale
+			}
+			row.Rates = append(row.Rates, cell)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	log.Printf("exchange_service.get_rate_matrix ok size=%d", len(currencies))
+	return result, nil
+}
+
+func (s *ExchangeService) convert(
+	resolver *rateResolver,
+	rounding conversion.RoundingMode,
+	baseCode string,
+	targetCode string,
+	amount decimal.Decimal,
+) (dto.ExchangeDto, error) {
 	if baseCode == "" || targetCode == "" {
 		log.Printf("exchange_service.exchange validation_error: empty code")
 		return dto.ExchangeDto{}, apperror.Validation("currency codes are required", "base or target code is empty")
@@ -131,50 +651,77 @@ func (s *ExchangeService) Exchange(
 		return dto.ExchangeDto{}, err
 	}
 
-	baseCurrency, err := s.currencyRepository.GetByCode(s.ctx, baseCode)
+	baseCurrency, targetCurrency, path, err := resolver.resolvePair(baseCode, targetCode)
 	if err != nil {
-		return dto.ExchangeDto{}, s.wrapCurrencyError("base currency", baseCode, err)
+		return dto.ExchangeDto{}, err
 	}
-
-	targetCurrency, err := s.currencyRepository.GetByCode(s.ctx, targetCode)
+	stale, err := resolver.checkFreshness(path)
 	if err != nil {
-		return dto.ExchangeDto{}, s.wrapCurrencyError("target currency", targetCode, err)
+		return dto.ExchangeDto{}, err
 	}
 
-	rate, err := s.exchangeRepository.GetRate(s.ctx, baseCurrency.ID, targetCurrency.ID)
-	if err != nil {
-		var notFoundErr *apperror.NotFoundError
-		if errors.As(err, &notFoundErr) {
-			log.Printf("exchange_service.exchange rate_not_found base_id=%d target_id=%d", baseCurrency.ID, targetCurrency.ID)
-			return dto.ExchangeDto{}, apperror.NotFound("exchange rate not found", "base_id="+fmt.Sprint(baseCurrency.ID)+" target_id="+fmt.Sprint(targetCurrency.ID))
-		}
-		log.Printf("exchange_service.exchange rate_error: %v", err)
-		return dto.ExchangeDto{}, apperror.Internal("get exchange rate", err.Error())
+	result := newExchangeDto(baseCurrency, targetCurrency, path, resolver.at)
+	result.Stale = stale
+	result.Mode = dto.ExchangeModeForward
+	result.Amount = amount
+	result.RawConvertAmount = amount.Mul(path.Rate())
+	result.ConvertAmount = conversion.Round(result.RawConvertAmount, targetCurrency.MinorUnits, rounding)
+	result.RoundingMode = string(rounding)
+	return result, nil
+}
+
+// ExchangeReverse computes how much of baseCode has to be converted to
+// receive exactly targetAmount of targetCode. The base amount is always
+// rounded up to the minor units of the base currency so the conversion never
+// falls short of the requested target amount.
+func (s *ExchangeService) ExchangeReverse(
+	baseCode string,
+	targetCode string,
+	targetAmount decimal.Decimal,
+	options ExchangeOptions,
+) (dto.ExchangeDto, error) {
+	log.Printf("exchange_service.exchange_reverse start base=%s target=%s target_amount=%s", baseCode, targetCode, targetAmount.String())
+	if baseCode == "" || targetCode == "" {
+		log.Printf("exchange_service.exchange_reverse validation_error: empty code")
+		return dto.ExchangeDto{}, apperror.Validation("currency codes are required", "base or target code is empty")
+	}
+	if targetAmount.LessThanOrEqual(decimal.Zero) {
+		log.Printf("exchange_service.exchange_reverse validation_error: non_positive target_amount=%s", targetAmount.String())
+		return dto.ExchangeDto{}, apperror.Validation("target amount must be greater than zero", "targetAmount="+targetAmount.String())
+	}
+	if err := validateAmountPrecision(targetAmount); err != nil {
+		log.Printf("exchange_service.exchange_reverse validation_error: %v", err)
+		return dto.ExchangeDto{}, err
 	}
 
-	result := dto.ExchangeDto{
-		ExchangeRate: dto.ExchangeRateDto{
-			BaseCurrency: dto.CurrencyDto{
-				ID:       baseCurrency.ID,
-				Code:     baseCurrency.Code,
-				FullName: baseCurrency.FullName,
-				Sign:     baseCurrency.Sign,
-			},
-			TargetCurrency: dto.CurrencyDto{
-				ID:       targetCurrency.ID,
-				Code:     targetCurrency.Code,
-				FullName: targetCurrency.FullName,
-				Sign:     targetCurrency.Sign,
-			},
-			Rate: rate,
-		},
-		Amount:        amount,
-		ConvertAmount: amount.Mul(rate),
+	resolver := s.newRateResolver(options.At)
+	baseCurrency, targetCurrency, path, err := resolver.resolvePair(baseCode, targetCode)
+	if err != nil {
+		return dto.ExchangeDto{}, err
 	}
-	log.Printf("exchange_service.exchange ok base=%s target=%s amount=%s converted=%s", baseCode, targetCode, amount.String(), result.ConvertAmount.String())
+	stale, err := resolver.checkFreshness(path)
+	if err != nil {
+		return dto.ExchangeDto{}, err
+	}
+
+	result := newExchangeDto(baseCurrency, targetCurrency, path, options.At)
+	result.Stale = stale
+	result.Mode = dto.ExchangeModeReverse
+	result.Amount = requiredBaseAmount(path, targetAmount, baseCurrency.MinorUnits)
+	result.ConvertAmount = targetAmount
+	result.RawConvertAmount = result.Amount.Mul(path.Rate())
+	result.RoundingMode = string(conversion.RoundUp)
+	log.Printf("exchange_service.exchange_reverse ok base=%s target=%s target_amount=%s amount=%s", baseCode, targetCode, targetAmount.String(), result.Amount.String())
 	return result, nil
 }
 
+func (s *ExchangeService) roundingMode(options ExchangeOptions) conversion.RoundingMode {
+	if options.Rounding == "" {
+		return s.config.RoundingMode
+	}
+	return options.Rounding
+}
+
 func (s *ExchangeService) wrapCurrencyError(currencyRole string, code string, err error) error {
 	var notFoundErr *apperror.NotFoundError
 	if errors.As(err, &notFoundErr) {
@@ -185,16 +732,115 @@ func (s *ExchangeService) wrapCurrencyError(currencyRole string, code string, er
 	return apperror.Internal("get "+currencyRole, err.Error())
 }
 
+func normalizeRateFilter(filter repository.RateFilter) repository.RateFilter {
+	filter.BaseCode = normalizeCode(filter.BaseCode)
+	filter.TargetCode = normalizeCode(filter.TargetCode)
+	filter.CurrencyCode = normalizeCode(filter.CurrencyCode)
+	return filter
+}
+
+func validateRateSort(sortBy string, direction pagination.SortDirection) error {
+	if sortBy != "" && sortBy != repository.RateSortID {
+		return apperror.Validation("invalid sort field", "sort must be "+repository.RateSortID)
+	}
+	if direction != "" && direction != pagination.SortAsc && direction != pagination.SortDesc {
+		return apperror.Validation(
+			"invalid sort direction",
+			"order must be "+string(pagination.SortAsc)+" or "+string(pagination.SortDesc),
+		)
+	}
+	return nil
+}
+
 func mapRate(rate entity.ExchangeRate) dto.ExchangeRateDto {
 	return dto.ExchangeRateDto{
 		ID:             rate.ID,
 		BaseCurrency:   mapCurrency(rate.BaseCurrency),
 		TargetCurrency: mapCurrency(rate.TargetCurrency),
 		Rate:           rate.Rate,
+		EffectiveFrom:  timePtr(rate.EffectiveFrom),
+		Sources:        rate.Sources,
+		UpdatedAt:      timePtr(rate.UpdatedAt),
+	}
+}
+
+func newExchangeDto(
+	baseCurrency entity.Currency,
+	targetCurrency entity.Currency,
+	path conversion.Path,
+	at *time.Time,
+) dto.ExchangeDto {
+	return dto.ExchangeDto{
+		ExchangeRate: dto.ExchangeRateDto{
+			ID:             directRateID(path),
+			BaseCurrency:   mapCurrency(baseCurrency),
+			TargetCurrency: mapCurrency(targetCurrency),
+			Rate:           path.Rate(),
+		},
+		Path: mapPath(path),
+		AsOf: at,
+	}
+}
+
+// requiredBaseAmount divides targetAmount by the path rate exactly and rounds
+// the quotient up to the given number of decimal places.
+func requiredBaseAmount(path conversion.Path, targetAmount decimal.Decimal, places int32) decimal.Decimal {
+	numerator, denominator := path.Fraction()
+	quotient, remainder := targetAmount.Mul(denominator).QuoRem(numerator, places)
+	if !remainder.IsZero() {
+		quotient = quotient.Add(decimal.New(1, -places))
 	}
+	return quotient
 }
 
-func validateRatePrecision(rate decimal.Decimal) error {
+func mapPath(path conversion.Path) dto.ConversionPathDto {
+	legs := make([]dto.ConversionLegDto, 0, len(path.Legs))
+	for _, leg := range path.Legs {
+		legs = append(legs, dto.ConversionLegDto{
+			RateID:     leg.Rate.ID,
+			Base:       leg.From().Code,
+			Target:     leg.To().Code,
+			StoredRate: leg.Rate.Rate,
+			Rate:       leg.Value(),
+			Inverted:   leg.Inverted,
+			UpdatedAt:  timePtr(leg.Rate.UpdatedAt),
+		})
+	}
+	return dto.ConversionPathDto{
+		Legs: legs,
+		Rate: path.Rate(),
+	}
+}
+
+// directRateID returns the id of the stored rate when the path is exactly
+// that rate used as is, and zero for inverted, cross or identity paths.
+func directRateID(path conversion.Path) int64 {
+	if path.Hops() == 1 && !path.Legs[0].Inverted {
+		return path.Legs[0].Rate.ID
+	}
+	return 0
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// validateRate accepts the rates the rate book can store and convert with:
+// positive, below 10^ExchangeRateMaxIntegerDigits and with no more than
+// ExchangeRateMaxScale decimal places.
+func validateRate(rate decimal.Decimal) error {
+	if !rate.IsPositive() {
+		return apperror.Validation("exchange rate must be greater than zero", "rate="+rate.String())
+	}
+	if rate.GreaterThanOrEqual(decimal.New(1, entity.ExchangeRateMaxIntegerDigits)) {
+		return apperror.Validation(
+			"exchange rate is too large",
+			"rate must have no more than "+fmt.Sprint(entity.ExchangeRateMaxIntegerDigits)+" integer digits",
+		)
+	}
 	if rate.Exponent() < -entity.ExchangeRateMaxScale {
 		return apperror.Validation(
 			"invalid exchange rate precision",
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"currency-exchange/internal/conversion"
+	"currency-exchange/internal/dto"
+	"currency-exchange/internal/entity"
+	apperror "currency-exchange/internal/error"
+)
+
+const (
+	// FreshnessWarn converts over stale rates and flags the result stale.
+	FreshnessWarn = "warn"
+	// FreshnessReject refuses to convert over stale rates.
+	FreshnessReject = "reject"
+)
+
+// FreshnessPolicy decides when a stored rate is too old to convert with. A
+// rate is stale once more than its maximum age has passed since UpdatedAt.
+// Conversions at a past moment are not checked.
+type FreshnessPolicy struct {
+	// MaxAge applies to every pair without a limit of its own; zero means
+	// such rates never go stale.
+	MaxAge time.Duration
+	// PairMaxAge overrides MaxAge for single stored pairs, keyed BASE/TARGET.
+	PairMaxAge map[string]time.Duration
+	// Mode is FreshnessWarn or FreshnessReject.
+	Mode string
+}
+
+// ParseFreshnessMode validates a freshness mode; empty means FreshnessWarn.
+func ParseFreshnessMode(value string) (string, error) {
+	switch value {
+	case "":
+		return FreshnessWarn, nil
+	case FreshnessWarn, FreshnessReject:
+		return value, nil
+	default:
+		return "", apperror.Validation(
+			"invalid freshness mode",
+			"mode must be "+FreshnessWarn+" or "+FreshnessReject,
+		)
+	}
+}
+
+func (p FreshnessPolicy) maxAge(rate entity.ExchangeRate) time.Duration {
+	if maxAge, ok := p.PairMaxAge[rate.BaseCurrency.Code+"/"+rate.TargetCurrency.Code]; ok {
+		return maxAge
+	}
+	return p.MaxAge
+}
+
+func (p FreshnessPolicy) isStale(rate entity.ExchangeRate, now time.Time) bool {
+	maxAge := p.maxAge(rate)
+	return maxAge > 0 && now.Sub(rate.UpdatedAt) > maxAge
+}
+
+// freshRates drops the rates that are stale at now.
+func (p FreshnessPolicy) freshRates(rates []entity.ExchangeRate, now time.Time) []entity.ExchangeRate {
+	fresh := make([]entity.ExchangeRate, 0, len(rates))
+	for _, rate := range rates {
+		if !p.isStale(rate, now) {
+			fresh = append(fresh, rate)
+		}
+	}
+	return fresh
+}
+
+// checkFreshness reports whether any leg of path is stale. Under the reject
+// policy a stale path is an error instead.
+func (r *rateResolver) checkFreshness(path conversion.Path) (bool, error) {
+	if r.at != nil {
+		return false, nil
+	}
+	policy := r.service.config.Freshness
+	now := time.Now().UTC()
+	var stale []string
+	for _, leg := range path.Legs {
+		if policy.isStale(leg.Rate, now) {
+			stale = append(stale, leg.Rate.BaseCurrency.Code+"/"+leg.Rate.TargetCurrency.Code+
+				" updated "+leg.Rate.UpdatedAt.Format(time.RFC3339))
+		}
+	}
+	if len(stale) == 0 {
+		return false, nil
+	}
+	if policy.Mode == FreshnessReject {
+		log.Printf("exchange_service.exchange stale_rate: %s", strings.Join(stale, ", "))
+		return true, apperror.Stale("exchange rate is stale", strings.Join(stale, ", "))
+	}
+	return true, nil
+}
+
+// GetStaleRates lists every stored rate that is stale under the freshness
+// policy, oldest first.
+func (s *ExchangeService) GetStaleRates() ([]dto.StaleRateDto, error) {
+	log.Printf("exchange_service.get_stale_rates start")
+	rates, err := s.exchangeRepository.GetAll(s.ctx)
+	if err != nil {
+		log.Printf("exchange_service.get_stale_rates error: %v", err)
+		return nil, apperror.Internal("get exchange rates", err.Error())
+	}
+
+	sort.SliceStable(rates, func(i, j int) bool { return rates[i].UpdatedAt.Before(rates[j].UpdatedAt) })
+	now := time.Now().UTC()
+	items := make([]dto.StaleRateDto, 0)
+	for _, rate := range rates {
+		if !s.config.Freshness.isStale(rate, now) {
+			continue
+		}
+		items = append(items, dto.StaleRateDto{
+			Rate:   mapRate(rate),
+			Age:    now.Sub(rate.UpdatedAt).Truncate(time.Second).String(),
+			MaxAge: s.config.Freshness.maxAge(rate).String(),
+		})
+	}
+	log.Printf("exchange_service.get_stale_rates ok count=%d", len(items))
+	return items, nil
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"currency-exchange/internal/conversion"
+	"currency-exchange/internal/entity"
+	apperror "currency-exchange/internal/error"
+
+	"github.com/shopspring/decimal"
+)
+
+// GuardrailPolicy limits how far a single write may move a rate away from
+// the rate the current rate book gives for the pair, directly, inverted or
+// across other currencies. Pairs without any rate yet are not limited.
+//
+// The rate book is read before the write transaction and not locked, so
+// concurrent writes of a pair are each checked against the same reference
+// and together may move the rate further than the limit. That race is
+// accepted: the guardrail catches mistyped and misparsed rates, not
+// competing writers, and serialising every rate write on the whole rate
+// book, which a triangulated reference depends on, would cost more than
+// it protects.
+type GuardrailPolicy struct {
+	// MaxChange is the largest accepted relative change, e.g. 0.2 for 20%;
+	// zero disables the guardrail for pairs without a limit of their own.
+	MaxChange decimal.Decimal
+	// PairMaxChange overrides MaxChange for single pairs, keyed BASE/TARGET.
+	// A limit also covers the inverse pair, which feeds the same
+	// conversions, unless that pair has a limit of its own.
+	PairMaxChange map[string]decimal.Decimal
+}
+
+// RateWriteOptions describe who writes rates and whether the change
+// guardrail may be exceeded.
+type RateWriteOptions struct {
+	// Actor is recorded in the rate history; empty means AnonymousActor.
+	Actor string
+	// Override applies rates beyond the guardrail. OverrideReason is then
+	// required and recorded in the history of every rate that needed it.
+	Override       bool
+	OverrideReason string
+}
+
+func (p GuardrailPolicy) maxChange(base string, target string) decimal.Decimal {
+	if maxChange, ok := p.PairMaxChange[base+"/"+target]; ok {
+		return maxChange
+	}
+	if maxChange, ok := p.PairMaxChange[target+"/"+base]; ok {
+		return maxChange
+	}
+	return p.MaxChange
+}
+
+func (p GuardrailPolicy) enabled() bool {
+	return p.MaxChange.IsPositive() || len(p.PairMaxChange) > 0
+}
+
+// normalizeRateWriteOptions defaults the actor and checks the lengths of
+// the recorded values and that an override gives its reason.
+func normalizeRateWriteOptions(options RateWriteOptions) (RateWriteOptions, error) {
+	actor, err := normalizeActor(options.Actor)
+	if err != nil {
+		return RateWriteOptions{}, err
+	}
+	options.Actor = actor
+	options.OverrideReason = strings.TrimSpace(options.OverrideReason)
+	if !options.Override {
+		options.OverrideReason = ""
+		return options, nil
+	}
+	if options.OverrideReason == "" {
+		return RateWriteOptions{}, apperror.Validation("override reason is required", "overrideReason is empty")
+	}
+	if utf8.RuneCountInString(options.OverrideReason) > entity.ExchangeRateMaxOverrideReasonLen {
+		return RateWriteOptions{}, apperror.Validation(
+			"invalid override reason",
+			"override reason must be at most "+fmt.Sprint(entity.ExchangeRateMaxOverrideReasonLen)+" symbols",
+		)
+	}
+	return options, nil
+}
+
+func normalizeActor(actor string) (string, error) {
+	actor = strings.TrimSpace(actor)
+	if actor == "" {
+		actor = AnonymousActor
+	}
+	if utf8.RuneCountInString(actor) > entity.ExchangeRateMaxActorLen {
+		return "", apperror.Validation(
+			"invalid actor",
+			"actor must be at most "+fmt.Sprint(entity.ExchangeRateMaxActorLen)+" symbols",
+		)
+	}
+	return actor, nil
+}
+
+// RateRejection is a rate held back by the rate change guardrail.
+type RateRejection struct {
+	Rate entity.ExchangeRate
+	Err  error
+}
+
+// rateGuard checks a batch of writes against the rate book it started from.
+// A rate it accepts becomes the reference for later writes of the same
+// pair, so the days of a history import are each compared with the day
+// before.
+type rateGuard struct {
+	service  *ExchangeService
+	book     *conversion.Graph
+	accepted map[currencyPair]decimal.Decimal
+}
+
+// newRateGuard returns a guard over current, or one that only stamps the
+// actor when the guardrail is disabled.
+func (s *ExchangeService) newRateGuard(current []entity.ExchangeRate) *rateGuard {
+	guard := &rateGuard{service: s, accepted: make(map[currencyPair]decimal.Decimal)}
+	if s.config.Guardrail.enabled() {
+		guard.book = conversion.NewGraph(current)
+	}
+	return guard
+}
+
+// loadRateGuard is newRateGuard over the current rate book, which is only
+// loaded when the guardrail is enabled.
+func (s *ExchangeService) loadRateGuard() (*rateGuard, error) {
+	if !s.config.Guardrail.enabled() {
+		return s.newRateGuard(nil), nil
+	}
+	rates, err := s.exchangeRepository.GetAll(s.ctx)
+	if err != nil {
+		log.Printf("exchange_service.guardrail rate_book_error: %v", err)
+		return nil, apperror.Internal("get exchange rates", err.Error())
+	}
+	return s.newRateGuard(rates), nil
+}
+
+// guardSingleRate checks one write against the current rate book.
+func (s *ExchangeService) guardSingleRate(rate *entity.ExchangeRate, options RateWriteOptions) error {
+	guard, err := s.loadRateGuard()
+	if err != nil {
+		return err
+	}
+	return guard.check(rate, options)
+}
+
+// GuardRates applies the rate change guardrail to rates written outside the
+// service, such as central bank imports, in order and over the rate book
+// current. It returns the rates to write, stamped with the actor and any
+// override reason, and the rates held back.
+func (s *ExchangeService) GuardRates(
+	current []entity.ExchangeRate,
+	rates []entity.ExchangeRate,
+	options RateWriteOptions,
+) ([]entity.ExchangeRate, []RateRejection, error) {
+	options, err := normalizeRateWriteOptions(options)
+	if err != nil {
+		log.Printf("exchange_service.guard_rates validation_error: %v", err)
+		return nil, nil, err
+	}
+	guard := s.newRateGuard(current)
+	accepted := make([]entity.ExchangeRate, 0, len(rates))
+	var rejected []RateRejection
+	for _, rate := range rates {
+		if err := guard.check(&rate, options); err != nil {
+			rejected = append(rejected, RateRejection{Rate: rate, Err: err})
+			continue
+		}
+		accepted = append(accepted, rate)
+	}
+	return accepted, rejected, nil
+}
+
+// check compares rate with its reference: the rate accepted last for the
+// pair in this batch, or else the rate the rate book gives for it. Both are
+// positive, as rate writes only accept positive rates and the rate book
+// graph leaves out any that were stored before. It
+// stamps the write with the actor and, when the change needed the
+// override, its reason.
+func (g *rateGuard) check(rate *entity.ExchangeRate, options RateWriteOptions) error {
+	rate.ChangedBy = options.Actor
+	if g.book == nil {
+		return nil
+	}
+	base, target := rate.BaseCurrency, rate.TargetCurrency
+	pair := currencyPair{baseID: base.ID, targetID: target.ID}
+	maxChange := g.service.config.Guardrail.maxChange(base.Code, target.Code)
+	if !maxChange.IsPositive() {
+		g.accepted[pair] = rate.Rate
+		return nil
+	}
+	reference, ok := g.accepted[pair]
+	if !ok {
+		path, found := g.book.FindPath(base.ID, target.ID, g.service.config.MaxHops)
+		if !found || path.Hops() == 0 {
+			g.accepted[pair] = rate.Rate
+			return nil
+		}
+		reference = path.Rate()
+	}
+	change := rate.Rate.Sub(reference).Abs().Div(reference)
+	if change.LessThanOrEqual(maxChange) {
+		g.accepted[pair] = rate.Rate
+		return nil
+	}
+	if options.Override {
+		log.Printf("exchange_service.guardrail override pair=%s/%s change=%s actor=%s",
+			base.Code, target.Code, change.StringFixed(4), options.Actor)
+		rate.OverrideReason = options.OverrideReason
+		g.accepted[pair] = rate.Rate
+		return nil
+	}
+
+	log.Printf("exchange_service.guardrail validation_error pair=%s/%s change=%s", base.Code, target.Code, change.StringFixed(4))
+	return apperror.Validation(
+		fmt.Sprintf(
+			"rate %s deviates %s%% from the current %s/%s rate %s, more than the allowed %s%%; override with a reason to apply it",
+			rate.Rate.String(),
+			change.Mul(decimal.NewFromInt(100)).StringFixed(2),
+			base.Code,
+			target.Code,
+			reference.Round(entity.ExchangeRateMaxScale).String(),
+			maxChange.Mul(decimal.NewFromInt(100)).StringFixed(2),
+		),
+		"change="+change.String(),
+	)
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestGuardrailPolicyMaxChange(t *testing.T) {
+	policy := GuardrailPolicy{
+		MaxChange: decimal.RequireFromString("0.2"),
+		PairMaxChange: map[string]decimal.Decimal{
+			"USD/JPY": decimal.RequireFromString("0.1"),
+			"EUR/USD": decimal.RequireFromString("0.05"),
+			"USD/EUR": decimal.RequireFromString("0.03"),
+		},
+	}
+	tests := []struct {
+		base, target string
+		want         string
+	}{
+		{base: "USD", target: "JPY", want: "0.1"},
+		{base: "JPY", target: "USD", want: "0.1"},
+		{base: "EUR", target: "USD", want: "0.05"},
+		{base: "USD", target: "EUR", want: "0.03"},
+		{base: "GBP", target: "USD", want: "0.2"},
+	}
+	for _, tt := range tests {
+		if got := policy.maxChange(tt.base, tt.target); !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("maxChange(%s, %s) = %s, want %s", tt.base, tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"currency-exchange/internal/dto"
+	"currency-exchange/internal/entity"
+	apperror "currency-exchange/internal/error"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ImportFormatCSV  = "csv"
+	ImportFormatJSON = "json"
+
+	// RateImportMaxRows caps the number of rows in a single rate import.
+	RateImportMaxRows = 10000
+)
+
+// rateImportRow is one input row before validation. The rate is kept as text
+// so a malformed number is reported against its row instead of failing the
+// whole import.
+type rateImportRow struct {
+	line   int
+	base   string
+	target string
+	rate   string
+}
+
+// ImportRates validates every row of a CSV or JSON rate list against the
+// current rate book and, unless dryRun is set, writes all new and changed
+// rates in one transaction, in which unchanged rates count as confirmed and
+// are marked as updated. When any row is invalid nothing is written and
+// the report says why for every row. A new or changed rate beyond the change
+// guardrail is invalid unless options override it.
+func (s *ExchangeService) ImportRates(
+	format string,
+	input io.Reader,
+	dryRun bool,
+	options RateWriteOptions,
+) (dto.RateImportDto, error) {
+	log.Printf("exchange_service.import_rates start format=%s dry_run=%t override=%t", format, dryRun, options.Override)
+	options, err := normalizeRateWriteOptions(options)
+	if err != nil {
+		log.Printf("exchange_service.import_rates validation_error: %v", err)
+		return dto.RateImportDto{}, err
+	}
+	rows, err := parseRateImport(format, input)
+	if err != nil {
+		log.Printf("exchange_service.import_rates validation_error: %v", err)
+		return dto.RateImportDto{}, err
+	}
+	if len(rows) == 0 {
+		log.Printf("exchange_service.import_rates validation_error: no rows")
+		return dto.RateImportDto{}, apperror.Validation("import must contain at least one row", "no rows")
+	}
+	if len(rows) > RateImportMaxRows {
+		log.Printf("exchange_service.import_rates validation_error: rows=%d", len(rows))
+		return dto.RateImportDto{}, apperror.Validation(
+			"import is too large",
+			"import must contain no more than "+fmt.Sprint(RateImportMaxRows)+" rows",
+		)
+	}
+
+	currencies, err := s.currencyRepository.GetAll(s.ctx)
+	if err != nil {
+		log.Printf("exchange_service.import_rates currencies_error: %v", err)
+		return dto.RateImportDto{}, apperror.Internal("get currencies", err.Error())
+	}
+	currencyByCode := make(map[string]entity.Currency, len(currencies))
+	for _, currency := range currencies {
+		currencyByCode[currency.Code] = currency
+	}
+	current, err := s.exchangeRepository.GetAll(s.ctx)
+	if err != nil {
+		log.Printf("exchange_service.import_rates rate_book_error: %v", err)
+		return dto.RateImportDto{}, apperror.Internal("get exchange rates", err.Error())
+	}
+	currentByPair := make(map[currencyPair]entity.ExchangeRate, len(current))
+	for _, rate := range current {
+		currentByPair[currencyPair{baseID: rate.BaseCurrency.ID, targetID: rate.TargetCurrency.ID}] = rate
+	}
+	guard := s.newRateGuard(current)
+
+	now := time.Now().UTC()
+	result := dto.RateImportDto{DryRun: dryRun, Rows: make([]dto.RateImportRowDto, 0, len(rows))}
+	seen := make(map[currencyPair]int)
+	var (
+		writes    []entity.ExchangeRate
+		confirmed []int64
+	)
+	for _, row := range rows {
+		report := dto.RateImportRowDto{Line: row.line, Base: normalizeCode(row.base), Target: normalizeCode(row.target)}
+		rate, err := validateRateImportRow(report, row.rate, currencyByCode, seen)
+		if err != nil {
+			report.Status = dto.RateImportInvalid
+			report.Error = &dto.BatchErrorDto{Kind: apperror.KindOf(err), Message: apperror.MessageOf(err)}
+			result.Invalid++
+			result.Rows = append(result.Rows, report)
+			continue
+		}
+		pair := currencyPair{baseID: rate.BaseCurrency.ID, targetID: rate.TargetCurrency.ID}
+		seen[pair] = row.line
+		report.Rate = &rate.Rate
+
+		previous, exists := currentByPair[pair]
+		if !exists || !previous.Rate.Equal(rate.Rate) {
+			if err := guard.check(&rate, options); err != nil {
+				report.Status = dto.RateImportInvalid
+				report.Error = &dto.BatchErrorDto{Kind: apperror.KindOf(err), Message: apperror.MessageOf(err)}
+				if exists {
+					report.PreviousRate = &previous.Rate
+				}
+				result.Invalid++
+				result.Rows = append(result.Rows, report)
+				continue
+			}
+		}
+		switch {
+		case !exists:
+			report.Status = dto.RateImportNew
+			result.New++
+		case previous.Rate.Equal(rate.Rate):
+			report.Status = dto.RateImportUnchanged
+			report.PreviousRate = &previous.Rate
+			result.Unchanged++
+		default:
+			report.Status = dto.RateImportChanged
+			report.PreviousRate = &previous.Rate
+			result.Changed++
+		}
+		if report.Status == dto.RateImportUnchanged {
+			confirmed = append(confirmed, previous.ID)
+		} else {
+			rate.EffectiveFrom = now
+			rate.UpdatedAt = now
+			writes = append(writes, rate)
+		}
+		result.Rows = append(result.Rows, report)
+	}
+
+	if dryRun || result.Invalid > 0 {
+		log.Printf("exchange_service.import_rates ok applied=false new=%d changed=%d unchanged=%d invalid=%d", result.New, result.Changed, result.Unchanged, result.Invalid)
+		return result, nil
+	}
+	if err := s.exchangeRepository.Refresh(s.ctx, writes, confirmed, now); err != nil {
+		log.Printf("exchange_service.import_rates error: %v", err)
+		return dto.RateImportDto{}, apperror.Internal("import exchange rates", err.Error())
+	}
+	result.Applied = true
+	log.Printf("exchange_service.import_rates ok applied=true new=%d changed=%d unchanged=%d", result.New, result.Changed, result.Unchanged)
+	return result, nil
+}
+
+func validateRateImportRow(
+	report dto.RateImportRowDto,
+	rawRate string,
+	currencyByCode map[string]entity.Currency,
+	seen map[currencyPair]int,
+) (entity.ExchangeRate, error) {
+	if report.Base == "" || report.Target == "" {
+		return entity.ExchangeRate{}, apperror.Validation("currency codes are required", "base or target code is empty")
+	}
+	baseCurrency, ok := currencyByCode[report.Base]
+	if !ok {
+		return entity.ExchangeRate{}, apperror.NotFound("base currency not found", "code="+report.Base)
+	}
+	targetCurrency, ok := currencyByCode[report.Target]
+	if !ok {
+		return entity.ExchangeRate{}, apperror.NotFound("target currency not found", "code="+report.Target)
+	}
+	if baseCurrency.ID == targetCurrency.ID {
+		return entity.ExchangeRate{}, apperror.Validation("base and target currency must differ", "code="+report.Base)
+	}
+	if line, ok := seen[currencyPair{baseID: baseCurrency.ID, targetID: targetCurrency.ID}]; ok {
+		return entity.ExchangeRate{}, apperror.Validation(
+			"duplicate currency pair",
+			report.Base+"/"+report.Target+" already given on line "+fmt.Sprint(line),
+		)
+	}
+	rate, err := decimal.NewFromString(strings.TrimSpace(rawRate))
+	if err != nil {
+		return entity.ExchangeRate{}, apperror.Validation("invalid rate", err.Error())
+	}
+	if err := validateRate(rate); err != nil {
+		return entity.ExchangeRate{}, err
+	}
+	return entity.ExchangeRate{BaseCurrency: baseCurrency, TargetCurrency: targetCurrency, Rate: rate}, nil
+}
+
+func parseRateImport(format string, input io.Reader) ([]rateImportRow, error) {
+	switch format {
+	case ImportFormatCSV:
+		return parseRateImportCSV(input)
+	case ImportFormatJSON:
+		return parseRateImportJSON(input)
+	default:
+		return nil, apperror.Validation(
+			"unsupported import format",
+			"format must be "+ImportFormatCSV+" or "+ImportFormatJSON,
+		)
+	}
+}
+
+// parseRateImportCSV reads base,target,rate records. A leading header row
+// with exactly those names is skipped.
+func parseRateImportCSV(input io.Reader) ([]rateImportRow, error) {
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	var rows []rateImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, apperror.Validation("invalid csv", err.Error())
+		}
+		line, _ := reader.FieldPos(0)
+		if len(rows) == 0 && isRateImportHeader(record) {
+			continue
+		}
+		rows = append(rows, rateImportRow{line: line, base: record[0], target: record[1], rate: record[2]})
+	}
+}
+
+func isRateImportHeader(record []string) bool {
+	return strings.EqualFold(strings.TrimSpace(record[0]), "base") &&
+		strings.EqualFold(strings.TrimSpace(record[1]), "target") &&
+		strings.EqualFold(strings.TrimSpace(record[2]), "rate")
+}
+
+// parseRateImportJSON reads an array of {"base", "target", "rate"} objects.
+func parseRateImportJSON(input io.Reader) ([]rateImportRow, error) {
+	var items []dto.RateImportItemRequest
+	decoder := json.NewDecoder(input)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&items); err != nil {
+		return nil, apperror.Validation("invalid json", err.Error())
+	}
+
+	rows := make([]rateImportRow, 0, len(items))
+	for i, item := range items {
+		rows = append(rows, rateImportRow{line: i + 1, base: item.Base, target: item.Target, rate: item.Rate.String()})
+	}
+	return rows, nil
+}
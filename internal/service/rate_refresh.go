@@ -0,0 +1,113 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"currency-exchange/internal/entity"
+	apperror "currency-exchange/internal/error"
+
+	"github.com/shopspring/decimal"
+)
+
+// RateQuote is a rate reported by a rate provider: one unit of Base buys
+// Rate units of Target.
+type RateQuote struct {
+	Base   string
+	Target string
+	Rate   decimal.Decimal
+	// Sources names the providers behind an aggregated quote; empty means
+	// the source the quote was refreshed from.
+	Sources []string
+}
+
+// RefreshResult summarises how a set of provider quotes was applied.
+type RefreshResult struct {
+	Written   int
+	Unchanged int
+	// Skipped lists the quoted pairs that could not be written, as
+	// BASE/TARGET.
+	Skipped []string
+	// Guarded lists the pairs whose quote moved further than the rate
+	// change guardrail allows, as BASE/TARGET. Their stored rate is kept.
+	Guarded []string
+}
+
+// RefreshRates writes provider quotes into the rate book in one
+// transaction. Quotes equal to the stored rate are not rewritten, so polling
+// does not grow the rate history, but they do count as fresh confirmation of
+// the stored rate. Pairs with a missing or disabled currency
+// and quotes that are not a valid rate are skipped, and quotes beyond the
+// rate change guardrail are held back, rather than failing the refresh.
+func (s *ExchangeService) RefreshRates(source string, quotes []RateQuote) (RefreshResult, error) {
+	log.Printf("exchange_service.refresh_rates start source=%s quotes=%d", source, len(quotes))
+	currencies, err := s.currencyRepository.GetAll(s.ctx)
+	if err != nil {
+		log.Printf("exchange_service.refresh_rates currencies_error: %v", err)
+		return RefreshResult{}, apperror.Internal("get currencies", err.Error())
+	}
+	byCode := make(map[string]entity.Currency, len(currencies))
+	for _, currency := range currencies {
+		byCode[currency.Code] = currency
+	}
+	stored, err := s.exchangeRepository.GetAll(s.ctx)
+	if err != nil {
+		log.Printf("exchange_service.refresh_rates rates_error: %v", err)
+		return RefreshResult{}, apperror.Internal("get exchange rates", err.Error())
+	}
+	current := make(map[[2]int64]entity.ExchangeRate, len(stored))
+	for _, rate := range stored {
+		current[[2]int64{rate.BaseCurrency.ID, rate.TargetCurrency.ID}] = rate
+	}
+	guard := s.newRateGuard(stored)
+
+	var (
+		result    RefreshResult
+		rates     []entity.ExchangeRate
+		confirmed []int64
+	)
+	now := time.Now().UTC()
+	for _, quote := range quotes {
+		pair := normalizeCode(quote.Base) + "/" + normalizeCode(quote.Target)
+		base, baseOK := byCode[normalizeCode(quote.Base)]
+		target, targetOK := byCode[normalizeCode(quote.Target)]
+		rate := quote.Rate.Round(entity.ExchangeRateMaxScale)
+		if !baseOK || !targetOK || base.Disabled || target.Disabled || base.ID == target.ID || !rate.IsPositive() {
+			result.Skipped = append(result.Skipped, pair)
+			continue
+		}
+		if previous, ok := current[[2]int64{base.ID, target.ID}]; ok && previous.Rate.Equal(rate) {
+			confirmed = append(confirmed, previous.ID)
+			result.Unchanged++
+			continue
+		}
+		sources := quote.Sources
+		if len(sources) == 0 {
+			sources = []string{source}
+		}
+		entityRate := entity.ExchangeRate{
+			BaseCurrency:   base,
+			TargetCurrency: target,
+			Rate:           rate,
+			EffectiveFrom:  now,
+			Sources:        sources,
+			UpdatedAt:      now,
+		}
+		if err := guard.check(&entityRate, RateWriteOptions{}); err != nil {
+			result.Guarded = append(result.Guarded, pair)
+			continue
+		}
+		rates = append(rates, entityRate)
+	}
+
+	if len(rates) > 0 || len(confirmed) > 0 {
+		if err := s.exchangeRepository.Refresh(s.ctx, rates, confirmed, now); err != nil {
+			log.Printf("exchange_service.refresh_rates error source=%s: %v", source, err)
+			return RefreshResult{}, apperror.Internal("refresh exchange rates", err.Error())
+		}
+	}
+	result.Written = len(rates)
+	log.Printf("exchange_service.refresh_rates ok source=%s written=%d unchanged=%d skipped=%d guarded=%d",
+		source, result.Written, result.Unchanged, len(result.Skipped), len(result.Guarded))
+	return result, nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"currency-exchange/internal/conversion"
+	"currency-exchange/internal/entity"
+	apperror "currency-exchange/internal/error"
+	"fmt"
+	"log"
+	"time"
+)
+
+type currencyLookup struct {
+	currency entity.Currency
+	err      error
+}
+
+type pathLookup struct {
+	path conversion.Path
+	err  error
+}
+
+type currencyPair struct {
+	baseID   int64
+	targetID int64
+}
+
+// rateResolver memoizes currency lookups, the rate book and the paths found
+// in it, so a group of conversions hits the database once per distinct
+// currency and once for the rate book.
+type rateResolver struct {
+	service    *ExchangeService
+	at         *time.Time
+	currencies map[string]currencyLookup
+	paths      map[currencyPair]pathLookup
+	graph      *conversion.Graph
+	// staleGraph also holds the stale rates left out of graph under the
+	// reject policy, to tell a pair reachable only over stale rates from an
+	// unknown one.
+	staleGraph *conversion.Graph
+}
+
+func (s *ExchangeService) newRateResolver(at *time.Time) *rateResolver {
+	return &rateResolver{
+		service:    s,
+		at:         at,
+		currencies: make(map[string]currencyLookup),
+		paths:      make(map[currencyPair]pathLookup),
+	}
+}
+
+func (r *rateResolver) currency(currencyRole string, code string) (entity.Currency, error) {
+	code = normalizeCode(code)
+	if lookup, ok := r.currencies[code]; ok {
+		if lookup.err != nil {
+			return entity.Currency{}, r.service.wrapCurrencyError(currencyRole, code, lookup.err)
+		}
+		return lookup.currency, nil
+	}
+
+	currency, err := r.service.currencyRepository.GetByCode(r.service.ctx, code)
+	r.currencies[code] = currencyLookup{currency: currency, err: err}
+	if err != nil {
+		return entity.Currency{}, r.service.wrapCurrencyError(currencyRole, code, err)
+	}
+	return currency, nil
+}
+
+// activeCurrency is currency that additionally rejects currencies disabled
+// by an operator, which must not take part in conversions.
+func (r *rateResolver) activeCurrency(currencyRole string, code string) (entity.Currency, error) {
+	currency, err := r.currency(currencyRole, code)
+	if err != nil {
+		return entity.Currency{}, err
+	}
+	if currency.Disabled {
+		log.Printf("exchange_service.exchange %s inactive code=%s", currencyRole, currency.Code)
+		return entity.Currency{}, apperror.Inactive(currencyRole+" is inactive", "code="+currency.Code)
+	}
+	return currency, nil
+}
+
+// resolvePair loads both currencies and the conversion path between them.
+func (r *rateResolver) resolvePair(baseCode string, targetCode string) (entity.Currency, entity.Currency, conversion.Path, error) {
+	baseCurrency, err := r.activeCurrency("base currency", baseCode)
+	if err != nil {
+		return entity.Currency{}, entity.Currency{}, conversion.Path{}, err
+	}
+
+	targetCurrency, err := r.activeCurrency("target currency", targetCode)
+	if err != nil {
+		return entity.Currency{}, entity.Currency{}, conversion.Path{}, err
+	}
+
+	path, err := r.getRate(baseCurrency, targetCurrency)
+	if err != nil {
+		return entity.Currency{}, entity.Currency{}, conversion.Path{}, err
+	}
+	return baseCurrency, targetCurrency, path, nil
+}
+
+// getRate finds the conversion path between two currencies over the rate
+// book that was in force at the resolver's moment, or the current one when
+// no moment was given. Under the reject policy paths avoid stale rates, and
+// a pair reachable only over stale rates is a stale error.
+func (r *rateResolver) getRate(base entity.Currency, target entity.Currency) (conversion.Path, error) {
+	pair := currencyPair{baseID: base.ID, targetID: target.ID}
+	if lookup, ok := r.paths[pair]; ok {
+		return lookup.path, lookup.err
+	}
+
+	if err := r.loadRateBook(); err != nil {
+		return conversion.Path{}, err
+	}
+
+	maxHops := r.service.config.MaxHops
+	path, ok := r.graph.FindPath(base.ID, target.ID, maxHops)
+	if !ok && r.staleGraph != nil {
+		if stalePath, found := r.staleGraph.FindPath(base.ID, target.ID, maxHops); found {
+			_, err := r.checkFreshness(stalePath)
+			r.paths[pair] = pathLookup{err: err}
+			return conversion.Path{}, err
+		}
+	}
+	if !ok {
+		log.Printf("exchange_service.get_rate not_found base=%s target=%s max_hops=%d", base.Code, target.Code, maxHops)
+		err := apperror.NotFound(
+			"exchange rate not found",
+			"base="+base.Code+" target="+target.Code+" max_hops="+fmt.Sprint(maxHops),
+		)
+		r.paths[pair] = pathLookup{err: err}
+		return conversion.Path{}, err
+	}
+
+	log.Printf("exchange_service.get_rate ok base=%s target=%s hops=%d", base.Code, target.Code, path.Hops())
+	r.paths[pair] = pathLookup{path: path}
+	return path, nil
+}
+
+func (r *rateResolver) loadRateBook() error {
+	if r.graph != nil {
+		return nil
+	}
+
+	var (
+		rates []entity.ExchangeRate
+		err   error
+	)
+	if r.at == nil {
+		rates, err = r.service.exchangeRepository.GetAll(r.service.ctx)
+	} else {
+		rates, err = r.service.exchangeRepository.GetAllAt(r.service.ctx, *r.at)
+	}
+	if err != nil {
+		log.Printf("exchange_service.get_rate rate_book_error: %v", err)
+		return apperror.Internal("get exchange rates", err.Error())
+	}
+
+	rates = enabledRates(rates)
+	if r.at == nil && r.service.config.Freshness.Mode == FreshnessReject {
+		fresh := r.service.config.Freshness.freshRates(rates, time.Now().UTC())
+		if len(fresh) < len(rates) {
+			r.staleGraph = conversion.NewGraph(rates)
+			rates = fresh
+		}
+	}
+	r.graph = conversion.NewGraph(rates)
+	return nil
+}
+
+// enabledRates drops the rates touching a disabled currency, so conversions
+// do not route through it either.
+func enabledRates(rates []entity.ExchangeRate) []entity.ExchangeRate {
+	enabled := make([]entity.ExchangeRate, 0, len(rates))
+	for _, rate := range rates {
+		if rate.BaseCurrency.Disabled || rate.TargetCurrency.Disabled {
+			continue
+		}
+		enabled = append(enabled, rate)
+	}
+	return enabled
+}